@@ -0,0 +1,170 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+// Flatten returns m as a group attr under key, each map entry being its own sub-attr,
+// so it is rendered as key.k=v instead of an opaque JSON blob.
+//
+// Nested maps are flattened recursively, non-string keys are formatted with fmt.Sprint.
+// If m is not a map, Flatten returns slog.Any(key, m).
+func Flatten(key string, m any) slog.Attr {
+	rv := reflect.ValueOf(m)
+	if rv.Kind() != reflect.Map {
+		return slog.Any(key, m)
+	}
+	return slog.Attr{Key: key, Value: slog.GroupValue(flattenMap(rv, 0)...)}
+}
+
+// maxFlattenDepth guards against self-referencing maps.
+const maxFlattenDepth = 16
+
+func flattenMap(rv reflect.Value, depth int) []slog.Attr {
+	if rv.IsNil() || rv.Len() == 0 {
+		return nil
+	}
+	type entry struct {
+		k string
+		v reflect.Value
+	}
+	entries := make([]entry, 0, rv.Len())
+	for iter := rv.MapRange(); iter.Next(); {
+		k := iter.Key()
+		var ks string
+		if k.Kind() == reflect.String {
+			ks = k.String()
+		} else {
+			ks = fmt.Sprint(k.Interface())
+		}
+		entries = append(entries, entry{k: ks, v: iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].k < entries[j].k })
+
+	attrs := make([]slog.Attr, 0, len(entries))
+	for _, e := range entries {
+		v := e.v
+		for v.Kind() == reflect.Interface && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.Map && depth < maxFlattenDepth {
+			attrs = append(attrs, slog.Attr{Key: e.k, Value: slog.GroupValue(flattenMap(v, depth+1)...)})
+			continue
+		}
+		attrs = append(attrs, slog.Any(e.k, e.v.Interface()))
+	}
+	return attrs
+}
+
+// RedactedValue replaces the values of the redacted fields (see StructAttrs).
+const RedactedValue = "REDACTED"
+
+// StructAttrs returns the exported fields of the struct (or pointer to struct) v as attrs,
+// keyed by their `log:"name"` tag, or the field name if the tag has no name.
+//
+// The fields tagged `log:"-"` are skipped, the values of the ones tagged `log:",redact"` are replaced by RedactedValue.
+// If v is not a struct, StructAttrs returns nil.
+//
+// The field list is cached per type.
+func StructAttrs(v any) []slog.Attr {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	fields := structFieldsOf(rv.Type())
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		if f.redact {
+			attrs = append(attrs, slog.String(f.key, RedactedValue))
+		} else {
+			attrs = append(attrs, slog.Any(f.key, rv.Field(f.index).Interface()))
+		}
+	}
+	return attrs
+}
+
+// WithStruct returns a Logger with the fields of the struct v added as attrs (see StructAttrs).
+func (lgr Logger) WithStruct(v any) Logger {
+	attrs := StructAttrs(v)
+	if len(attrs) == 0 {
+		return lgr
+	}
+	lgr2 := newLogger()
+	lgr2.p.Store(slog.New(lgr.load().Handler().WithAttrs(attrs)))
+	return lgr2
+}
+
+type structField struct {
+	key    string
+	index  int
+	redact bool
+}
+
+// structFields caches the []structField of the struct types.
+var structFields sync.Map
+
+func structFieldsOf(t reflect.Type) []structField {
+	if fields, ok := structFields.Load(t); ok {
+		return fields.([]structField)
+	}
+	fields := make([]structField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("log"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, structField{key: name, index: i, redact: opts == "redact"})
+	}
+	structFields.Store(t, fields)
+	return fields
+}
+
+// TTLKey is the key of the retention hint attr (see TTL).
+const TTLKey = "_ttl"
+
+// TTL returns a retention hint attr: the record should be kept for (at least) d by the downstream sinks
+// (0 means indefinitely, as for audit records), so the retention policy is attached to the record.
+//
+// This is a convention only: the handlers of this package do not act on it, and the sinks
+// may read it with RecordTTL. The JSON handler renders it as an integer (nanoseconds).
+func TTL(d time.Duration) slog.Attr { return slog.Duration(TTLKey, d) }
+
+// RecordTTL returns the retention hint (see TTL) of the record, if it has one (at the top level).
+func RecordTTL(r slog.Record) (time.Duration, bool) {
+	var d time.Duration
+	var ok bool
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key != TTLKey {
+			return true
+		}
+		if a.Value.Kind() == slog.KindDuration {
+			d, ok = a.Value.Duration(), true
+		}
+		return false
+	})
+	return d, ok
+}
@@ -12,15 +12,18 @@ package zlog
 import (
 	"bytes"
 	"context"
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/UNO-SOFT/zlog/v2/slog"
@@ -63,11 +66,166 @@ type ConsoleHandler struct {
 	withGroup []string
 	withAttrs []slog.Attr
 	attrBuf   bytes.Buffer
-	UseColor  bool
+	// prevAttrs of DiffAttrs
+	prevAttrs *map[string]string
+	// sourceWidth is the rolling maximum width of the source column (see SourceWidth)
+	sourceWidth *atomic.Int32
+	// UseColor enables the colors (NewConsoleHandler decides it by the writer and the environment).
+	UseColor bool
+	// LineEnding terminates each line (defaults to "\n"; use "\r\n" for Windows tools).
+	LineEnding string
+	// Fallback receives the record when the write to the primary writer fails
+	// (for example to spill critical logs into a file on a broken pipe).
+	// Its own failure is not propagated further to other fallbacks.
+	Fallback slog.Handler
+	// AttrColors colors the attr values by their kind (when UseColor is true); off if nil.
+	AttrColors *AttrColors
+	// MultilineDetail keeps the first line of a multi-line message as the message,
+	// and moves the rest into a "detail" attr, keeping one line per record.
+	MultilineDetail bool
+	// AttrsBeforeMessage renders the attrs before the (quoted) message,
+	// so the message is the last part of the line.
+	AttrsBeforeMessage bool
+	// BoolGlyphs renders the bool values as ✓ (true) and ✗ (false).
+	BoolGlyphs bool
+	// DiffAttrs (EXPERIMENTAL) colors the attrs which are new or changed since the previous record
+	// (of this handler and the ones derived from it) with the given color, instead of using AttrColors;
+	// off if 0 or UseColor is false.
+	//
+	// This is a heuristic debug aid for tailing the logs of repeatedly logged state.
+	DiffAttrs Color
+	// Formatters format the values of the attrs by their key (regardless of their kind),
+	// for example to add a unit suffix or humanize a byte count.
+	//
+	// They are used only for the console output (JSON stays raw), and must be cheap.
+	Formatters map[string]func(slog.Value) string
+	// IndentJSON renders the complex (map, struct, slice) values of the record's attrs,
+	// whose compact JSON encoding is longer than IndentJSON bytes, as indented JSON blocks
+	// following the main line; off (compact, single line) if 0.
+	IndentJSON int
+	// ColorizeMessage (EXPERIMENTAL) renders the logfmt-ish messages (such as "op=create id=5 ok")
+	// unquoted, with their key=value tokens colored by AttrColors (when UseColor is true).
+	ColorizeMessage bool
+	// AttrEncoder renders the attrs instead of the default, slog.TextHandler based encoder
+	// (which serializes the rendering with a mutex).
+	// Set it before deriving handlers (WithAttrs, WithGroup) from this one.
+	//
+	// A custom encoder is responsible for the value normalization (see ReplaceAttr, KeepEmpty, BoolGlyphs, Formatters).
+	AttrEncoder AttrEncoder
+	// GroupPrefix prepends the group path (of WithGroup) to the message, as "[group.subgroup] message".
+	GroupPrefix bool
+	// TimeAttrFormat is the layout of the time.Time attr values (for example TimeFormat, as the timestamps),
+	// or RelativeTimeFormat; the slog.TextHandler's RFC3339 if empty. JSON stays RFC3339.
+	TimeAttrFormat string
+	// SourceWidth pads the "[file:line]" source column to this width, so the messages start at the same column.
+	// If negative, the width is the rolling maximum of the source columns seen so far
+	// (by this handler and the ones derived from it), bounded by -SourceWidth.
+	SourceWidth int
+	// FormatMessage transforms the message (after GroupPrefix, before quoting and coloring),
+	// for example to prepend an emoji by level; the identity if nil.
+	//
+	// It runs on the hot path, for each record, so it must be cheap.
+	FormatMessage func(level slog.Level, msg string) string
+}
+
+// boolGlyph returns ✓ for true, ✗ for false.
+func boolGlyph(b bool) string {
+	if b {
+		return "✓"
+	}
+	return "✗"
 }
 
 // HandlerOptions wraps slog.HandlerOptions, stripping source prefix.
-type HandlerOptions struct{ slog.HandlerOptions }
+type HandlerOptions struct {
+	slog.HandlerOptions
+	// EpochMillis replaces the "time" attr (RFC3339 string) with
+	// "ts_ms" (milliseconds since the Unix epoch, as an integer) in the JSON output.
+	// The two are mutually exclusive.
+	EpochMillis bool
+	// KeepEmpty keeps the empty values (key="", key=null) instead of dropping them
+	// (which is done by the ReplaceAttr of DefaultHandlerOptions and the ConsoleHandler).
+	KeepEmpty bool
+	// LevelNum adds the integer value of the level as "level_num" next to the "level" in the JSON output,
+	// for numeric filtering (for example TraceLevel is -5, AuditLevel is 12).
+	LevelNum bool
+	// SourceLevel restricts AddSource to the records at or above this level (for example WarnLevel),
+	// both on the console and in the JSON output; all levels if nil.
+	SourceLevel slog.Leveler
+	// Location is the time zone of the timestamps (both console and JSON); time.Local if nil.
+	Location *time.Location
+	// RecordSeparator replaces the "\n" the JSON handler writes after each record (see NewRecordSeparatorWriter).
+	RecordSeparator string
+}
+
+// JSONSeqSeparator is the RS character, which starts each record in RFC 7464 (application/json-seq).
+const JSONSeqSeparator = "\x1e"
+
+// NewRecordSeparatorWriter returns a writer for the JSON handler (which writes each record with one Write call, ending with "\n"),
+// replacing the "\n" at the end of each record with sep.
+//
+// JSONSeqSeparator is special: it is written before each record, and the "\n" is kept, as RFC 7464 prescribes.
+func NewRecordSeparatorWriter(w io.Writer, sep string) io.Writer {
+	if sep == "" || sep == "\n" {
+		return w
+	}
+	return &recordSeparatorWriter{w: w, sep: sep}
+}
+
+type recordSeparatorWriter struct {
+	w   io.Writer
+	sep string
+	buf []byte
+	mu  sync.Mutex
+}
+
+func (rw *recordSeparatorWriter) Write(p []byte) (int, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.sep == JSONSeqSeparator {
+		rw.buf = append(append(rw.buf[:0], rw.sep...), p...)
+	} else {
+		rw.buf = append(append(rw.buf[:0], bytes.TrimSuffix(p, []byte{'\n'})...), rw.sep...)
+	}
+	if _, err := rw.w.Write(rw.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// keptEmpty is an empty value which is not dropped by ensurePrintableValueIsEmpty,
+// as it is a json.Marshaler.
+type keptEmpty struct{ json string }
+
+func (ke keptEmpty) MarshalJSON() ([]byte, error) { return []byte(ke.json), nil }
+func (ke keptEmpty) MarshalText() ([]byte, error) { return []byte(ke.json), nil }
+func (ke keptEmpty) String() string               { return ke.json }
+
+// keepEmptyAttr replaces the empty KindAny value of the attr with a value which is not dropped:
+// an empty string (as a KindString, rendered as key="") or a keptEmpty (key=null, key=[], key={}).
+func keepEmptyAttr(a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindAny {
+		return a
+	}
+	v := a.Value
+	if !ensurePrintableValueIsEmpty(&v) {
+		return a
+	}
+	ke := keptEmpty{json: "null"}
+	if v.Kind() == slog.KindString { // an empty string or fmt.Stringer
+		ke.json = `""`
+	} else if x := a.Value.Any(); x != nil {
+		if b, err := json.Marshal(x); err == nil {
+			ke.json = string(b)
+		}
+	}
+	if ke.json == `""` {
+		a.Value = slog.StringValue("")
+	} else {
+		a.Value = slog.AnyValue(ke)
+	}
+	return a
+}
 
 var (
 	jsonMarshalableMu  sync.Mutex
@@ -75,6 +233,316 @@ var (
 	jsonMarshalableEnc = json.NewEncoder(&jsonMarshalableBuf)
 )
 
+// MaxJSONDepth is the maximum nesting depth (of structs, maps, slices, pointers) of the complex values
+// encoded as JSON by ensurePrintableValueIsEmpty: the deeper levels, the cycles (references back to
+// a value being encoded) and the values beyond MaxJSONNodes are replaced by "…".
+//
+// MaxJSONNodes is the maximum number of nested values visited by one walk of a value
+// (checking and truncating), so a densely self-referencing value cannot hang the logging.
+//
+// MaxJSONLen is the maximum length of that JSON encoding, the rest is replaced by "…"; unlimited if 0.
+var (
+	MaxJSONDepth = 32
+	MaxJSONNodes = 4096
+	MaxJSONLen   = 8 << 10
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// canNest reports whether the values of type t may have nested values
+// (as encoding/json sees them: the json.Marshaler and encoding.TextMarshaler types are leaves).
+func canNest(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return !t.Implements(jsonMarshalerType) && !t.Implements(textMarshalerType)
+	}
+	return false
+}
+
+// isJSONLeaf reports whether rv has no nested values for encoding/json:
+// its type cannot nest (see canNest), or it is addressable and its pointer is a marshaler.
+func isJSONLeaf(rv reflect.Value) bool {
+	if !canNest(rv.Type()) {
+		return true
+	}
+	if !rv.CanAddr() {
+		return false
+	}
+	pt := reflect.PointerTo(rv.Type())
+	return pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType)
+}
+
+// jsonLeaf returns the value of the leaf rv for encoding/json: the pointer to it if the pointer is a marshaler,
+// its basic value if it cannot be interfaced (reached through an unexported embedded field).
+func jsonLeaf(rv reflect.Value) any {
+	if !rv.CanInterface() {
+		switch rv.Kind() {
+		case reflect.Bool:
+			return rv.Bool()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return rv.Uint()
+		case reflect.Float32, reflect.Float64:
+			return rv.Float()
+		case reflect.String:
+			return rv.String()
+		}
+		return "…"
+	}
+	if canNest(rv.Type()) && rv.CanAddr() {
+		return rv.Addr().Interface()
+	}
+	return rv.Interface()
+}
+
+// jsonWalk is one walk of a value by exceeds or truncate: the node budget and the references on the current path.
+type jsonWalk struct {
+	path  map[jsonRef]struct{}
+	nodes int
+}
+
+// jsonRef identifies a referenced value (by pointer, map or slice).
+type jsonRef struct {
+	p   uintptr
+	n   int
+	typ reflect.Type
+}
+
+// reset the walk for a new pass, with a full node budget.
+func (w *jsonWalk) reset() {
+	w.nodes = MaxJSONNodes
+	clear(w.path)
+}
+
+// enter accounts for the node rv, and reports whether the walk can descend into it:
+// false if the budget is exhausted or rv is a reference on the current path (a cycle).
+// If ok, leave(ref) must be called when the walk returns from rv.
+func (w *jsonWalk) enter(rv reflect.Value) (ref jsonRef, ok bool) {
+	if w.nodes--; w.nodes < 0 {
+		return ref, false
+	}
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map:
+		ref = jsonRef{p: rv.Pointer(), typ: rv.Type()}
+	case reflect.Slice:
+		if rv.Len() == 0 {
+			return ref, true
+		}
+		ref = jsonRef{p: rv.Pointer(), n: rv.Len(), typ: rv.Type()}
+	default:
+		return ref, true
+	}
+	if w.path == nil {
+		w.path = make(map[jsonRef]struct{})
+	}
+	if _, ok := w.path[ref]; ok {
+		return ref, false
+	}
+	w.path[ref] = struct{}{}
+	return ref, true
+}
+
+// leave removes ref (returned by enter) from the current path.
+func (w *jsonWalk) leave(ref jsonRef) {
+	if ref.typ != nil {
+		delete(w.path, ref)
+	}
+}
+
+// exceeds reports whether rv must be truncated: it is nested deeper than MaxJSONDepth
+// (depth being the current depth), has a cycle, or has more than MaxJSONNodes nested values.
+func (w *jsonWalk) exceeds(rv reflect.Value, depth int) bool {
+	if !rv.IsValid() || isJSONLeaf(rv) {
+		return false
+	}
+	if depth >= MaxJSONDepth {
+		return true
+	}
+	if (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) && rv.IsNil() {
+		return false
+	}
+	ref, ok := w.enter(rv)
+	if !ok {
+		return true
+	}
+	defer w.leave(ref)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return w.exceeds(rv.Elem(), depth+1)
+	case reflect.Struct:
+		t := rv.Type()
+		for i := 0; i < rv.NumField(); i++ {
+			if f := t.Field(i); (f.IsExported() || f.Anonymous) && w.exceeds(rv.Field(i), depth+1) {
+				return true
+			}
+		}
+	case reflect.Map:
+		if !canNest(rv.Type().Elem()) {
+			return false
+		}
+		for iter := rv.MapRange(); iter.Next(); {
+			if w.exceeds(iter.Value(), depth+1) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if !canNest(rv.Type().Elem()) {
+			return false
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if w.exceeds(rv.Index(i), depth+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// truncate returns a copy of rv as generic maps and slices (the leaves and the values
+// without nested values kept as is), up to MaxJSONDepth,
+// the deeper levels, the cycles and the nodes beyond the budget replaced by "…".
+//
+// The structs follow encoding/json's field naming, "omitempty" and embedded field promotion.
+func (w *jsonWalk) truncate(rv reflect.Value, depth int) any {
+	if !rv.IsValid() {
+		return nil
+	}
+	if isJSONLeaf(rv) {
+		return jsonLeaf(rv)
+	}
+	if depth >= MaxJSONDepth {
+		return "…"
+	}
+	if (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) && rv.IsNil() {
+		return nil
+	}
+	ref, ok := w.enter(rv)
+	if !ok {
+		return "…"
+	}
+	defer w.leave(ref)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return w.truncate(rv.Elem(), depth+1)
+	case reflect.Struct:
+		m := make(map[string]any, rv.NumField())
+		w.truncateFields(m, rv, depth)
+		return m
+	case reflect.Map:
+		if !canNest(rv.Type().Elem()) && rv.CanInterface() {
+			return rv.Interface()
+		}
+		m := make(map[string]any, rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			m[fmt.Sprint(jsonLeaf(iter.Key()))] = w.truncate(iter.Value(), depth+1)
+		}
+		return m
+	default: // reflect.Slice, reflect.Array
+		if !canNest(rv.Type().Elem()) && rv.CanInterface() {
+			return rv.Interface()
+		}
+		a := make([]any, rv.Len())
+		for i := range a {
+			a[i] = w.truncate(rv.Index(i), depth+1)
+		}
+		return a
+	}
+}
+
+// truncateFields adds the fields of the struct rv to m, the fields of the (untagged) embedded structs
+// promoted, unless m already has a field with that name (the shallower field wins).
+func (w *jsonWalk) truncateFields(m map[string]any, rv reflect.Value, depth int) {
+	t := rv.Type()
+	var embedded []reflect.Value
+	for i := 0; i < rv.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		fv := rv.Field(i)
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && canNest(ft) {
+				if fv.Kind() == reflect.Pointer {
+					if fv.IsNil() {
+						continue
+					}
+					fv = fv.Elem()
+				}
+				embedded = append(embedded, fv)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyJSONValue(fv) {
+			continue
+		}
+		m[name] = w.truncate(fv, depth+1)
+	}
+	for _, fv := range embedded {
+		sub := make(map[string]any, fv.NumField())
+		w.truncateFields(sub, fv, depth)
+		for k, v := range sub {
+			if _, ok := m[k]; !ok {
+				m[k] = v
+			}
+		}
+	}
+}
+
+// isEmptyJSONValue reports whether rv is empty for encoding/json's "omitempty".
+func isEmptyJSONValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return rv.IsZero()
+	}
+	return false
+}
+
+// nilString is the rendering of the nil values.
+const nilString = "<nil>"
+
+// isNil reports whether v is nil: a nil interface, or a nil pointer.
+//
+// The nil slices and maps are not nil here, just empty - as the nil funcs and chans.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.UnsafePointer:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// ensurePrintableValueIsEmpty replaces the KindAny value with a printable one, and reports whether it is empty
+// (to be dropped).
+//
+// A nil (see isNil) is rendered as "<nil>" (never dropped),
+// nil slices and maps are empty (as any empty slice or map), nil funcs and chans are empty, too.
+//
+// The other values are encoded as JSON, without the encoder's trailing newline;
+// the ones encoded as "", [], {} or null are empty.
 func ensurePrintableValueIsEmpty(value *slog.Value) (isEmpty bool) {
 	if value.Kind() != slog.KindAny {
 		return false
@@ -90,9 +558,10 @@ func ensurePrintableValueIsEmpty(value *slog.Value) (isEmpty bool) {
 		}
 	}()
 	v := value.Any()
-	if v == nil {
+	if isNil(v) {
 		ok = true
-		return true
+		*value = slog.StringValue(nilString)
+		return false
 	}
 	switch x := v.(type) {
 	case string:
@@ -101,14 +570,11 @@ func ensurePrintableValueIsEmpty(value *slog.Value) (isEmpty bool) {
 		return x == ""
 	case error:
 		ok = true
-		if x == nil {
-			return true
-		}
 		*value = slog.StringValue(x.Error())
 		return false
 	case json.Marshaler:
 		ok = true
-		return x == nil
+		return false
 	case fmt.Stringer:
 		ok = true
 		s := x.String()
@@ -192,14 +658,22 @@ func ensurePrintableValueIsEmpty(value *slog.Value) (isEmpty bool) {
 			ok = false
 			return rv.IsNil()
 		default:
+			var w jsonWalk
+			if w.reset(); w.exceeds(rv, 0) {
+				w.reset()
+				v = w.truncate(rv, 0)
+			}
 			jsonMarshalableMu.Lock()
 			defer jsonMarshalableMu.Unlock()
 			jsonMarshalableBuf.Reset()
 			if ok = jsonMarshalableEnc.Encode(v) == nil; ok {
-				switch x := jsonMarshalableBuf.String(); x {
+				switch x := strings.TrimSuffix(jsonMarshalableBuf.String(), "\n"); x {
 				case `""`, `[]`, `{}`, "null":
 					return true
 				default:
+					if MaxJSONLen > 0 && len(x) > MaxJSONLen {
+						x = strings.ToValidUTF8(x[:MaxJSONLen], "") + "…"
+					}
 					*value = slog.StringValue(x)
 					return false
 				}
@@ -210,6 +684,69 @@ func ensurePrintableValueIsEmpty(value *slog.Value) (isEmpty bool) {
 	return false
 }
 
+// MaxConsoleSliceLen is the maximum number of the elements of a slice value rendered on the console,
+// the rest is replaced by an ellipsis.
+var MaxConsoleSliceLen = 32
+
+// consoleSliceValue replaces the non-empty slice (or array) value with its compact, bracketed form:
+// [1,2,3] instead of the JSON encoding, nested slices included.
+// It reports whether the value has been replaced.
+func consoleSliceValue(value *slog.Value) bool {
+	if value.Kind() != slog.KindAny {
+		return false
+	}
+	x := value.Any()
+	switch x.(type) {
+	case nil, json.Marshaler, fmt.Stringer, error:
+		return false
+	}
+	rv := reflect.ValueOf(x)
+	if !isConsoleSlice(rv) || rv.Len() == 0 {
+		return false
+	}
+	*value = slog.StringValue(string(appendConsoleSlice(nil, rv, 0)))
+	return true
+}
+
+// isConsoleSlice reports whether rv is a slice or array - but not a []byte.
+func isConsoleSlice(rv reflect.Value) bool {
+	return (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8
+}
+
+func appendConsoleSlice(dst []byte, rv reflect.Value, depth int) []byte {
+	dst = append(dst, '[')
+	n := rv.Len()
+	if n > MaxConsoleSliceLen {
+		n = MaxConsoleSliceLen
+	}
+	for i := 0; i < n; i++ {
+		if i != 0 {
+			dst = append(dst, ',')
+		}
+		elem := rv.Index(i)
+		for elem.Kind() == reflect.Interface && !elem.IsNil() {
+			elem = elem.Elem()
+		}
+		if isConsoleSlice(elem) && depth < maxFlattenDepth {
+			dst = appendConsoleSlice(dst, elem, depth+1)
+			continue
+		}
+		v := slog.AnyValue(elem.Interface())
+		if v.Kind() == slog.KindAny {
+			ensurePrintableValueIsEmpty(&v)
+		}
+		if s := v.String(); s == "" {
+			dst = append(dst, `""`...)
+		} else {
+			dst = append(dst, s...)
+		}
+	}
+	if n < rv.Len() {
+		dst = append(dst, ",…"...)
+	}
+	return append(dst, ']')
+}
+
 func newConsoleHandlerOptions() HandlerOptions {
 	opts := DefaultConsoleHandlerOptions
 	opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
@@ -218,7 +755,7 @@ func newConsoleHandlerOptions() HandlerOptions {
 			// These are handled directly
 			return zeroAttr
 		default:
-			if a.Value.Kind() == slog.KindAny {
+			if a.Value.Kind() == slog.KindAny && !consoleSliceValue(&a.Value) {
 				if ensurePrintableValueIsEmpty(&a.Value) {
 					return zeroAttr
 				}
@@ -229,15 +766,34 @@ func newConsoleHandlerOptions() HandlerOptions {
 	return opts
 }
 
-// NewConsoleHandler returns a new ConsoleHandler which writes to w.
+// nonNilWriter returns w, or io.Discard if w is nil, so a nil writer does not panic at log time.
+func nonNilWriter(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+	return w
+}
+
+// NewConsoleHandler returns a new ConsoleHandler which writes to w (io.Discard if nil).
+//
+// UseColor is decided by the environment and w: NO_COLOR and TERM=dumb turn it off, FORCE_COLOR on;
+// otherwise terminals, git's pager and non-file writers (such as a bytes.Buffer) get colors,
+// other files (a pipe to grep, a regular file) don't.
+//
+// This is a behavior change: UseColor used to be always true.
+// Set it (or FORCE_COLOR=1) to get the colors back.
 func NewConsoleHandler(level slog.Leveler, w io.Writer) *ConsoleHandler {
+	w = nonNilWriter(w)
 	opts := newConsoleHandlerOptions()
 	opts.Level = level
 	h := ConsoleHandler{
-		UseColor:       true,
+		UseColor:       shouldUseColor(w),
+		LineEnding:     "\n",
 		HandlerOptions: opts,
 		w:              w,
 		mu:             new(sync.Mutex),
+		prevAttrs:      new(map[string]string),
+		sourceWidth:    new(atomic.Int32),
 	}
 	h.initAttrHandler()
 	return &h
@@ -248,11 +804,20 @@ var DefaultHandlerOptions = HandlerOptions{HandlerOptions: slog.HandlerOptions{
 	AddSource: true,
 	ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
 		switch a.Key {
-		case "time", "level", "source":
+		case "level":
+			if len(groups) == 0 {
+				if lvl, ok := a.Value.Any().(slog.Level); ok && lvl == AuditLevel {
+					a.Value = slog.StringValue("AUDIT")
+				}
+			}
+			return a
+		case "time", "source":
 			return a
 		default:
 			if a.Value.Kind() == slog.KindAny {
-				if ensurePrintableValueIsEmpty(&a.Value) {
+				if isNil(a.Value.Any()) {
+					a.Value = slog.AnyValue(nil) // null
+				} else if ensurePrintableValueIsEmpty(&a.Value) {
 					return zeroAttr
 				}
 			}
@@ -264,27 +829,119 @@ var DefaultHandlerOptions = HandlerOptions{HandlerOptions: slog.HandlerOptions{
 // DefaultConsoleHandlerOptions *does not* add the source.
 var DefaultConsoleHandlerOptions = HandlerOptions{}
 
-// MaybeConsoleHandler returns an slog.JSONHandler if w is a terminal, and slog.TextHandler otherwise.
+// MaybeConsoleHandler returns a ConsoleHandler if w is a terminal, and an slog.JSONHandler otherwise.
 func MaybeConsoleHandler(level slog.Leveler, w io.Writer) slog.Handler {
-	if IsTerminal(w) {
-		return NewConsoleHandler(level, w)
-	}
 	opts := DefaultHandlerOptions
 	opts.Level = level
+	return opts.MaybeConsoleHandler(w)
+}
+
+// MaybeConsoleHandler returns a ConsoleHandler if w is a terminal, and an slog.JSONHandler otherwise,
+// both with the level and Location of opts, so the timestamps are in the same time zone.
+func (opts HandlerOptions) MaybeConsoleHandler(w io.Writer) slog.Handler {
+	if IsTerminal(w) {
+		h := NewConsoleHandler(opts.Level, w)
+		h.Location = opts.Location
+		return h
+	}
 	return opts.NewJSONHandler(w)
 }
 
+// NewJSONHandler returns an slog.JSONHandler with the options, writing to w (io.Discard if nil).
 func (opts HandlerOptions) NewJSONHandler(w io.Writer) slog.Handler {
+	w = NewRecordSeparatorWriter(nonNilWriter(w), opts.RecordSeparator)
 	o := opts.HandlerOptions
 	addSource := o.AddSource
-	o.AddSource = false
+	if addSource {
+		// The source is added by the JSONHandler (so at the top level, not in the open groups),
+		// trimmed here; customSourceHandler suppresses it by zeroing the PC.
+		replaceAttr := o.ReplaceAttr
+		o.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == "source" {
+				if src, ok := a.Value.Any().(*slog.Source); ok {
+					if src == nil || src.File == "" {
+						return zeroAttr
+					}
+					a.Value = slog.StringValue(trimRootPath(src.File) + ":" + strconv.Itoa(src.Line))
+				}
+			}
+			if replaceAttr == nil {
+				return a
+			}
+			return replaceAttr(groups, a)
+		}
+	}
+	if opts.KeepEmpty && o.ReplaceAttr != nil {
+		replaceAttr := o.ReplaceAttr
+		o.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			return replaceAttr(groups, keepEmptyAttr(a))
+		}
+	}
+	if loc := opts.Location; loc != nil {
+		replaceAttr := o.ReplaceAttr
+		o.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == "time" {
+				if t, ok := a.Value.Any().(time.Time); ok {
+					a.Value = slog.TimeValue(t.In(loc))
+				}
+			}
+			if replaceAttr == nil {
+				return a
+			}
+			return replaceAttr(groups, a)
+		}
+	}
+	if opts.EpochMillis {
+		replaceAttr := o.ReplaceAttr
+		o.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == "time" {
+				if t, ok := a.Value.Any().(time.Time); ok {
+					return slog.Int64("ts_ms", t.UnixMilli())
+				}
+			}
+			if replaceAttr == nil {
+				return a
+			}
+			return replaceAttr(groups, a)
+		}
+	}
+	if opts.LevelNum {
+		replaceAttr := o.ReplaceAttr
+		o.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			// the members of the inlined group below are passed to ReplaceAttr again:
+			// unwrap them, without calling replaceAttr a second time
+			if x, ok := a.Value.Any().(levelNumDone); ok && len(groups) == 0 {
+				a.Value = x.Value
+				return a
+			}
+			lvl, ok := a.Value.Any().(slog.Level)
+			if !ok || len(groups) != 0 || a.Key != "level" {
+				if replaceAttr == nil {
+					return a
+				}
+				return replaceAttr(groups, a)
+			}
+			if replaceAttr != nil {
+				a = replaceAttr(groups, a)
+			}
+			if l, ok := a.Value.Any().(slog.Level); ok {
+				a.Value = slog.StringValue(l.String())
+			}
+			a.Value = slog.AnyValue(levelNumDone{a.Value})
+			// an empty-keyed group is inlined
+			return slog.Attr{Value: slog.GroupValue(a, slog.Any("level_num", levelNumDone{slog.IntValue(int(lvl))}))}
+		}
+	}
 	hndl := slog.NewJSONHandler(w, &o)
 	if !addSource {
 		return hndl
 	}
-	return customSourceHandler{Handler: &syncHandler{Handler: hndl}}
+	return customSourceHandler{Handler: &syncHandler{Handler: hndl}, sourceLevel: opts.SourceLevel}
 }
 
+// levelNumDone marks the level and level_num attrs of HandlerOptions.LevelNum, already passed to ReplaceAttr.
+type levelNumDone struct{ slog.Value }
+
 type syncHandler struct {
 	slog.Handler
 	mu sync.Mutex
@@ -296,6 +953,9 @@ func (h *syncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	return &syncHandler{Handler: h.Handler.WithAttrs(attrs)}
 }
 func (h *syncHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	return &syncHandler{Handler: h.Handler.WithGroup(name)}
@@ -308,32 +968,75 @@ func (h *syncHandler) Handle(ctx context.Context, r slog.Record) error {
 
 type customSourceHandler struct {
 	slog.Handler
+	sourceLevel slog.Leveler
+	noSource    bool
 }
 
 func (h customSourceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return customSourceHandler{Handler: h.Handler.WithAttrs(attrs)}
+	h.Handler = h.Handler.WithAttrs(attrs)
+	return h
 }
 func (h customSourceHandler) WithGroup(name string) slog.Handler {
-	return customSourceHandler{Handler: h.Handler.WithGroup(name)}
+	if name == "" {
+		return h
+	}
+	h.Handler = h.Handler.WithGroup(name)
+	return h
+}
+func (h customSourceHandler) WithSource(addSource bool) slog.Handler {
+	h.noSource = !addSource
+	return h
 }
+
+// Handle implements slog.Handler.Handle.
+//
+// It does not filter by level: that is the job of Enabled
+// (so a wrapping LevelHandler, or the context's level, can enable more records).
 func (h customSourceHandler) Handle(ctx context.Context, r slog.Record) error {
-	if !h.Handler.Enabled(ctx, r.Level) {
-		return nil
-	}
-	//fmt.Printf("customSourceHandler.Handle r=%+v PC=%d\n", r, r.PC)
-	if r.PC != 0 {
-		// https://pkg.go.dev/log/slog#example-package-Wrapping
-		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
-		if file, line := frame.File, frame.Line; file != "" {
-			buf := bufPool.Get().(*bytes.Buffer)
-			defer bufPool.Put(buf)
-			buf.Reset()
-			r.AddAttrs(slog.String("source", trimRootPath(file)+":"+strconv.Itoa(line)))
-		}
+	if h.noSource || !sourceEnabled(h.sourceLevel, r.Level) {
+		r.PC = 0
 	}
 	return h.Handler.Handle(ctx, r)
 }
 
+// sourceEnabled reports whether the source is to be added to the record of the level:
+// minLevel is nil or level is at least minLevel.
+func sourceEnabled(minLevel slog.Leveler, level slog.Level) bool {
+	return minLevel == nil || level >= minLevel.Level()
+}
+
+// shouldUseColor decides whether to use colors when writing to w:
+//
+//   - NO_COLOR (https://no-color.org) set: no colors
+//   - FORCE_COLOR set (and not "0" or "false"): colors
+//   - TERM=dumb: no colors
+//   - w is a terminal: colors
+//   - w is a pipe to git's pager (GIT_PAGER_IN_USE is set): colors
+//   - w is some other file (pipe, regular file): no colors
+//   - w is not a file (for example a bytes.Buffer): colors,
+//     as the ConsoleHandler has been explicitly asked for.
+func shouldUseColor(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	if s, ok := os.LookupEnv("FORCE_COLOR"); ok && s != "0" && s != "false" {
+		return true
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	if _, ok := w.(interface{ Fd() uintptr }); !ok {
+		return true
+	}
+	if IsTerminal(w) {
+		return true
+	}
+	if s := os.Getenv("GIT_PAGER_IN_USE"); s != "" && s != "0" && s != "false" {
+		return true
+	}
+	return false
+}
+
 // IsTerminal returns whether the io.Writer is a terminal or not.
 func IsTerminal(w io.Writer) bool {
 	if fder, ok := w.(interface{ Fd() uintptr }); ok {
@@ -343,8 +1046,57 @@ func IsTerminal(w io.Writer) bool {
 }
 
 // Enabled implements slog.Handler.Enabled.
+//
+// AuditLevel is always enabled.
 func (h *ConsoleHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return level >= h.HandlerOptions.Level.Level()
+	return level >= AuditLevel || level >= h.HandlerOptions.Level.Level()
+}
+
+// RelativeTimeFormat as ConsoleHandler.TimeAttrFormat renders the time.Time attr values
+// relative to the current time, as "in 1m30s" or "1m30s ago".
+const RelativeTimeFormat = "relative"
+
+// formatTimeAttr formats t by TimeAttrFormat, in Location.
+func (h *ConsoleHandler) formatTimeAttr(t time.Time) string {
+	if h.TimeAttrFormat == RelativeTimeFormat {
+		d, suffix := time.Until(t), ""
+		if d < 0 {
+			d, suffix = -d, " ago"
+		}
+		if d >= time.Second {
+			d = d.Round(time.Second)
+		} else {
+			d = d.Round(time.Millisecond)
+		}
+		if suffix == "" {
+			return "in " + d.String()
+		}
+		return d.String() + suffix
+	}
+	if h.Location != nil {
+		t = t.In(h.Location)
+	}
+	return t.Format(h.TimeAttrFormat)
+}
+
+// sourceColumnWidth returns the width of the source column for src, by SourceWidth.
+func (h *ConsoleHandler) sourceColumnWidth(src string) int {
+	if h.SourceWidth >= 0 {
+		return h.SourceWidth
+	}
+	if h.sourceWidth == nil {
+		return 0
+	}
+	width := int32(min(displayWidth(src), -h.SourceWidth))
+	for {
+		old := h.sourceWidth.Load()
+		if width <= old {
+			return int(old)
+		}
+		if h.sourceWidth.CompareAndSwap(old, width) {
+			return int(width)
+		}
+	}
 }
 
 // Handle implements slog.Handler.Handle.
@@ -352,75 +1104,304 @@ func (h *ConsoleHandler) Handle(ctx context.Context, r slog.Record) error {
 	if h == nil {
 		return nil
 	}
+	orig := r
+	if h.MultilineDetail {
+		r = splitMultilineMessage(r)
+	}
+	r = recordConsoleValues(r)
+	var blocks []string
+	if h.IndentJSON > 0 {
+		r, blocks = h.extractIndented(r)
+	}
 	buf := bufPool.Get().(*bytes.Buffer)
 	defer bufPool.Put(buf)
 	buf.Reset()
 	tmp := make([]byte, 0, len(TimeFormat)+len(r.Message))
-	buf.Write(r.Time.AppendFormat(tmp[:0], TimeFormat))
+	t := r.Time
+	if h.Location != nil {
+		t = t.In(h.Location)
+	}
+	buf.Write(t.AppendFormat(tmp[:0], TimeFormat))
 	if TimeFormat == DefaultTimeFormat {
+		// The .999 layout drops the trailing zeros of the fraction - and the dot, too, for whole seconds.
+		if buf.Len() == len("15:04:05") {
+			buf.WriteByte('.')
+		}
 		for n := len(DefaultTimeFormat) - buf.Len(); n > 0; n-- {
 			buf.WriteByte('0')
 		}
 	}
 	buf.WriteString(" ")
 
-	var level string
-	if r.Level < slog.LevelInfo {
-		level = "DBG"
-	} else if r.Level < slog.LevelWarn {
-		level = "INF"
-	} else if r.Level < slog.LevelError {
-		level = "WRN"
-	} else {
-		level = "ERR"
-	}
+	level := levelLabel(r.Level)
 	if h.UseColor {
 		level = addColorToLevel(level)
 	}
 	buf.WriteString(level)
 	buf.WriteString(" ")
 
-	if h.AddSource && r.PC != 0 {
+	if h.AddSource && r.PC != 0 && sourceEnabled(h.SourceLevel, r.Level) {
 		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
 		file, line := frame.File, frame.Line
 		if file != "" {
-			buf.WriteByte('[')
-			buf.WriteString(trimRootPath(file))
-			buf.WriteString(":")
-			buf.Write([]byte(strconv.Itoa(line)))
-			buf.WriteString("] ")
+			src := "[" + trimRootPath(file) + ":" + strconv.Itoa(line) + "]"
+			buf.WriteString(padRight(src, h.sourceColumnWidth(src)))
+			buf.WriteString(" ")
 		}
 	}
 
-	buf.Write(strconv.AppendQuote(tmp[:0], r.Message))
+	message := r.Message
+	if h.GroupPrefix && len(h.withGroup) != 0 {
+		message = "[" + strings.Join(h.withGroup, ".") + "] " + message
+	}
+	if h.FormatMessage != nil {
+		message = h.FormatMessage(r.Level, message)
+	}
+	var msg []byte
+	if h.ColorizeMessage && h.UseColor && h.AttrColors != nil {
+		var colored bytes.Buffer
+		if h.AttrColors.colorizeMessage(&colored, message) {
+			msg = colored.Bytes()
+		}
+	}
+	if msg == nil {
+		msg = strconv.AppendQuote(tmp[:0], message)
+	}
+	if !h.AttrsBeforeMessage {
+		buf.Write(msg)
+	}
 
 	var err error
 	if r.NumAttrs() != 0 {
 		func() {
-			h.mu.Lock()
-			defer h.mu.Unlock()
-			h.attrBuf.Reset()
-
+			var attrs []byte
 			r.Time, r.Level, r.PC, r.Message = time.Time{}, 0, 0, ""
-			err = h.attrHandler.Handle(ctx, r)
-			if h.attrBuf.Len() != 0 {
+			if h.AttrEncoder != nil {
+				attrs = h.AttrEncoder.AppendAttrs(nil, r)
+			} else {
+				h.mu.Lock()
+				defer h.mu.Unlock()
+				h.attrBuf.Reset()
+				err = h.attrHandler.Handle(ctx, r)
+				attrs = h.attrBuf.Bytes()
+			}
+			if len(attrs) == 0 {
+				return
+			}
+			if h.UseColor && h.DiffAttrs != 0 {
+				if h.AttrEncoder != nil {
+					h.mu.Lock()
+					defer h.mu.Unlock()
+				}
+				if h.prevAttrs == nil {
+					h.prevAttrs = new(map[string]string)
+				}
+				var colored bytes.Buffer
+				*h.prevAttrs = diffAttrs(&colored, attrs, *h.prevAttrs, h.DiffAttrs)
+				attrs = colored.Bytes()
+			} else if h.UseColor && h.AttrColors != nil {
+				var colored bytes.Buffer
+				h.AttrColors.colorizeAttrs(&colored, attrs)
+				attrs = colored.Bytes()
+			}
+			if h.AttrsBeforeMessage {
+				buf.Write(bytes.TrimRight(attrs, "\n"))
 				buf.WriteByte(' ')
-				buf.Write(h.attrBuf.Bytes())
+			} else {
+				buf.WriteByte(' ')
+				buf.Write(attrs)
 			}
 		}()
 	}
-	if buf.Len() != 0 && buf.Bytes()[buf.Len()-1] != '\n' {
-		buf.WriteByte('\n')
+	if h.AttrsBeforeMessage {
+		buf.Write(msg)
+	}
+	lineEnding := h.LineEnding
+	if lineEnding == "" {
+		lineEnding = "\n"
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte(lineEnding)) {
+		if b := buf.Bytes(); len(b) != 0 && b[len(b)-1] == '\n' {
+			buf.Truncate(len(b) - 1)
+		}
+		buf.WriteString(lineEnding)
+	}
+	for _, block := range blocks {
+		buf.WriteString(strings.ReplaceAll(block, "\n", lineEnding))
+		buf.WriteString(lineEnding)
 	}
-	if _, wErr := h.w.Write(buf.Bytes()); wErr != nil && err == nil {
-		err = wErr
+	if _, wErr := h.w.Write(buf.Bytes()); wErr != nil {
+		if err == nil {
+			err = wErr
+		}
+		if h.Fallback != nil && ctx.Value(inFallbackKey{}) == nil {
+			if fErr := handleFallback(ctx, h.Fallback, orig); fErr == nil && err == wErr {
+				err = nil
+			}
+		}
 	}
 
 	return err
 }
 
+// ConsoleValuer is implemented by the types which have a console-specific representation.
+//
+// It is used only by the ConsoleHandler, and takes precedence over slog.LogValuer,
+// fmt.Stringer and the JSON encoding there; the other handlers (JSON) ignore it.
+type ConsoleValuer interface {
+	ConsoleString() string
+}
+
+// consoleValue returns the ConsoleString of the value (of the groups' members, recursively), if it is a ConsoleValuer.
+func consoleValue(v slog.Value) (slog.Value, bool) {
+	switch v.Kind() {
+	case slog.KindAny, slog.KindLogValuer:
+		if cv, ok := v.Any().(ConsoleValuer); ok {
+			return slog.StringValue(cv.ConsoleString()), true
+		}
+	case slog.KindGroup:
+		group := v.Group()
+		var changed bool
+		for i, a := range group {
+			if v2, ok := consoleValue(a.Value); ok {
+				if !changed {
+					group = append([]slog.Attr(nil), group...)
+					changed = true
+				}
+				group[i].Value = v2
+			}
+		}
+		if changed {
+			return slog.GroupValue(group...), true
+		}
+	}
+	return v, false
+}
+
+// consoleValues replaces the ConsoleValuer values of the attrs (on a copy, if there is any).
+func consoleValues(attrs []slog.Attr) []slog.Attr {
+	var changed bool
+	for i, a := range attrs {
+		if v, ok := consoleValue(a.Value); ok {
+			if !changed {
+				attrs = append([]slog.Attr(nil), attrs...)
+				changed = true
+			}
+			attrs[i].Value = v
+		}
+	}
+	return attrs
+}
+
+// recordConsoleValues replaces the ConsoleValuer values of the record's attrs.
+func recordConsoleValues(r slog.Record) slog.Record {
+	var found bool
+	r.Attrs(func(a slog.Attr) bool {
+		_, found = consoleValue(a.Value)
+		return !found
+	})
+	if !found {
+		return r
+	}
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool { attrs = append(attrs, a); return true })
+	r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r2.AddAttrs(consoleValues(attrs)...)
+	return r2
+}
+
+// extractIndented removes the attrs to be rendered as indented JSON blocks from the record,
+// and returns them as "  key={...}" blocks.
+func (h *ConsoleHandler) extractIndented(r slog.Record) (slog.Record, []string) {
+	var blocks []string
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		if b, ok := h.indentedBlock(a); ok {
+			blocks = append(blocks, b)
+		} else {
+			attrs = append(attrs, a)
+		}
+		return true
+	})
+	if len(blocks) == 0 {
+		return r, nil
+	}
+	r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r2.AddAttrs(attrs...)
+	return r2, blocks
+}
+
+// indentedBlock returns the indented JSON block of the attr, iff its value is complex and long enough.
+func (h *ConsoleHandler) indentedBlock(a slog.Attr) (string, bool) {
+	v := a.Value.Resolve()
+	if v.Kind() != slog.KindAny {
+		return "", false
+	}
+	x := v.Any()
+	switch x.(type) {
+	case error, fmt.Stringer:
+		return "", false
+	}
+	rv := reflect.ValueOf(x)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+	default:
+		return "", false
+	}
+	if b, err := json.Marshal(x); err != nil || len(b) <= h.IndentJSON {
+		return "", false
+	}
+	b, err := json.MarshalIndent(x, "  ", "  ")
+	if err != nil {
+		return "", false
+	}
+	key := a.Key
+	if len(h.withGroup) != 0 {
+		key = strings.Join(h.withGroup, ".") + "." + key
+	}
+	return "  " + key + "=" + string(b), true
+}
+
+type inFallbackKey struct{}
+
+// handleFallback calls fallback.Handle, guarded against panics and loops (fallbacks of fallbacks).
+func handleFallback(ctx context.Context, fallback slog.Handler, r slog.Record) (err error) {
+	if !fallback.Enabled(ctx, r.Level) {
+		return nil
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("fallback panicked: %v", p)
+		}
+	}()
+	return fallback.Handle(context.WithValue(ctx, inFallbackKey{}, true), r)
+}
+
 func (h *ConsoleHandler) initAttrHandler() {
-	h.attrHandler = slog.NewTextHandler(&h.attrBuf, &h.HandlerOptions.HandlerOptions)
+	opts := h.HandlerOptions.HandlerOptions
+	replaceAttr := opts.ReplaceAttr
+	opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if h.KeepEmpty {
+			a = keepEmptyAttr(a)
+		}
+		if f := h.Formatters[a.Key]; f != nil && a.Value.Kind() != slog.KindGroup {
+			a.Value = slog.StringValue(f(a.Value))
+		}
+		if h.BoolGlyphs && a.Value.Kind() == slog.KindBool {
+			a.Value = slog.StringValue(boolGlyph(a.Value.Bool()))
+		}
+		if h.TimeAttrFormat != "" && a.Value.Kind() == slog.KindTime {
+			a.Value = slog.StringValue(h.formatTimeAttr(a.Value.Time()))
+		}
+		if replaceAttr == nil {
+			return a
+		}
+		return replaceAttr(groups, a)
+	}
+	h.attrHandler = slog.NewTextHandler(&h.attrBuf, &opts)
 	if len(h.withAttrs) != 0 {
 		h.attrHandler = h.attrHandler.WithAttrs(h.withAttrs).(*slog.TextHandler)
 	}
@@ -434,15 +1415,35 @@ func (h *ConsoleHandler) initAttrHandler() {
 // WithAttrs implements slog.Handler.WithAttrs.
 func (h *ConsoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	h2 := *h
-	h2.withAttrs = append(append(make([]slog.Attr, 0, len(h2.withAttrs)+len(attrs)), h2.withAttrs...), attrs...)
+	h2.withAttrs = append(append(make([]slog.Attr, 0, len(h2.withAttrs)+len(attrs)), h2.withAttrs...), consoleValues(attrs)...)
+	if h2.AttrEncoder != nil {
+		h2.AttrEncoder = h2.AttrEncoder.WithAttrs(consoleValues(attrs))
+	}
 	h2.initAttrHandler()
 	return &h2
 }
 
 // WithGroup implements slog.Handler.WithGroup.
 func (h *ConsoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
 	h2 := *h
 	h2.withGroup = append(append(make([]string, 0, len(h2.withGroup)+1), h2.withGroup...), name)
+	if h2.AttrEncoder != nil {
+		h2.AttrEncoder = h2.AttrEncoder.WithGroup(name)
+	}
+	h2.initAttrHandler()
+	return &h2
+}
+
+// WithSource returns a new ConsoleHandler with AddSource set to addSource.
+func (h *ConsoleHandler) WithSource(addSource bool) slog.Handler {
+	if h.AddSource == addSource {
+		return h
+	}
+	h2 := *h
+	h2.AddSource = addSource
 	h2.initAttrHandler()
 	return &h2
 }
@@ -467,20 +1468,79 @@ func (c Color) Add(s string) string {
 	return fmt.Sprintf("\x1b[%dm%s\x1b[0m", uint8(c), s)
 }
 
+// levelColors is copy-on-write, to be safe for concurrent logging and customization.
+type levelColors struct {
+	m       map[string]Color
+	unknown Color
+}
+
 var (
-	levelToColor = map[string]Color{
-		"DBG": Magenta,
-		"INF": Blue,
-		"WRN": Yellow,
-		"ERR": Red,
-	}
-	unknownLevelColor = Red
+	levelColorsMu sync.Mutex
+	levelToColor  atomic.Pointer[levelColors]
 )
 
-func addColorToLevel(level string) string {
-	color, ok := levelToColor[level]
+func init() {
+	levelToColor.Store(&levelColors{
+		m: map[string]Color{
+			"DBG": Magenta,
+			"INF": Blue,
+			"WRN": Yellow,
+			"ERR": Red,
+			"AUD": Cyan,
+		},
+		unknown: Red,
+	})
+}
+
+// SetLevelColor sets the color of the level label ("DBG", "INF", "WRN", "ERR", "AUD").
+//
+// Safe to call concurrently with logging.
+func SetLevelColor(level string, color Color) {
+	levelColorsMu.Lock()
+	defer levelColorsMu.Unlock()
+	old := levelToColor.Load()
+	m := make(map[string]Color, len(old.m)+1)
+	for k, v := range old.m {
+		m[k] = v
+	}
+	m[level] = color
+	levelToColor.Store(&levelColors{m: m, unknown: old.unknown})
+}
+
+// SetUnknownLevelColor sets the color of the unknown level labels.
+//
+// Safe to call concurrently with logging.
+func SetUnknownLevelColor(color Color) {
+	levelColorsMu.Lock()
+	defer levelColorsMu.Unlock()
+	old := levelToColor.Load()
+	levelToColor.Store(&levelColors{m: old.m, unknown: color})
+}
+
+// levelLabel returns the three-letter label of the level.
+func levelLabel(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return "DBG"
+	case level < slog.LevelWarn:
+		return "INF"
+	case level < slog.LevelError:
+		return "WRN"
+	case level < AuditLevel:
+		return "ERR"
+	default:
+		return "AUD"
+	}
+}
+
+// levelColor returns the color of the level label.
+func levelColor(level string) Color {
+	lc := levelToColor.Load()
+	color, ok := lc.m[level]
 	if !ok {
-		color = unknownLevelColor
+		color = lc.unknown
 	}
-	return color.Add(level)
+	return color
 }
+
+func addColorToLevel(level string) string { return levelColor(level).Add(level) }
@@ -0,0 +1,177 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+// AttrColors configures the coloring of the attr values on the console, by their kind.
+//
+// As the attrs are already rendered as text, the kind is inferred from the rendered value.
+type AttrColors struct {
+	// Kinds maps the kind of the value to its color (KindInt64 is used for all numbers).
+	Kinds map[slog.Kind]Color
+	// Error is the color of the error (ErrorKey) attrs' values (no color if 0).
+	Error Color
+}
+
+// DefaultAttrColors colors numbers cyan, strings green, booleans yellow, durations magenta, times blue and errors red.
+var DefaultAttrColors = AttrColors{
+	Kinds: map[slog.Kind]Color{
+		slog.KindInt64:    Cyan,
+		slog.KindString:   Green,
+		slog.KindBool:     Yellow,
+		slog.KindDuration: Magenta,
+		slog.KindTime:     Blue,
+	},
+	Error: Red,
+}
+
+// colorizeAttrs re-colors the key=value tokens of the text rendered attrs.
+func (ac *AttrColors) colorizeAttrs(dst *bytes.Buffer, text []byte) {
+	for len(text) != 0 {
+		if text[0] == ' ' || text[0] == '\n' {
+			dst.WriteByte(text[0])
+			text = text[1:]
+			continue
+		}
+		key, rest := cutToken(text, '=')
+		if len(rest) == 0 || rest[0] != '=' {
+			dst.Write(text)
+			return
+		}
+		value, rest := cutToken(rest[1:], ' ')
+		ac.writeKeyValue(dst, key, value)
+		text = rest
+	}
+}
+
+// writeKeyValue writes key=value, the value colored by its (inferred) kind.
+func (ac *AttrColors) writeKeyValue(dst *bytes.Buffer, key, value []byte) {
+	dst.Write(key)
+	dst.WriteByte('=')
+	var color Color
+	if k := string(key); k == ErrorKey || strings.HasSuffix(k, "."+ErrorKey) {
+		color = ac.Error
+	} else {
+		color = ac.Kinds[inferKind(value)]
+	}
+	if color == 0 {
+		dst.Write(value)
+	} else {
+		dst.WriteString(color.Add(string(value)))
+	}
+}
+
+// rKeyValueMessage matches the messages with key=value tokens.
+var rKeyValueMessage = regexp.MustCompile(`(?:^|\s)[A-Za-z_][A-Za-z0-9_.-]*=[^\s=]`)
+
+// colorizeMessage writes the logfmt-ish message (unquoted) with its key=value tokens colored,
+// and reports whether the message is such (single line, printable, having key=value tokens) -
+// nothing is written otherwise.
+func (ac *AttrColors) colorizeMessage(dst *bytes.Buffer, msg string) bool {
+	if !rKeyValueMessage.MatchString(msg) || strconv.Quote(msg) != `"`+msg+`"` {
+		return false
+	}
+	text := []byte(msg)
+	for len(text) != 0 {
+		if text[0] == ' ' {
+			dst.WriteByte(' ')
+			text = text[1:]
+			continue
+		}
+		key, rest := cutToken(text, '=')
+		if len(key) == 0 || len(rest) == 0 || rest[0] != '=' {
+			word, rest := cutToken(text, ' ')
+			dst.Write(word)
+			text = rest
+			continue
+		}
+		value, rest := cutToken(rest[1:], ' ')
+		ac.writeKeyValue(dst, key, value)
+		text = rest
+	}
+	return true
+}
+
+// diffAttrs colors the key=value tokens of the text rendered attrs
+// which are new or changed since the previous record (prev), and returns the current ones.
+func diffAttrs(dst *bytes.Buffer, text []byte, prev map[string]string, color Color) map[string]string {
+	cur := make(map[string]string, len(prev))
+	for len(text) != 0 {
+		if text[0] == ' ' || text[0] == '\n' {
+			dst.WriteByte(text[0])
+			text = text[1:]
+			continue
+		}
+		key, rest := cutToken(text, '=')
+		if len(rest) == 0 || rest[0] != '=' {
+			dst.Write(text)
+			break
+		}
+		value, rest := cutToken(rest[1:], ' ')
+		k, v := string(key), string(value)
+		cur[k] = v
+		if old, ok := prev[k]; ok && old == v {
+			dst.WriteString(k + "=" + v)
+		} else {
+			dst.WriteString(color.Add(k + "=" + v))
+		}
+		text = rest
+	}
+	return cur
+}
+
+// cutToken returns the (possibly quoted) token before the separator (or newline), and the rest (starting with sep).
+func cutToken(text []byte, sep byte) (token, rest []byte) {
+	if len(text) != 0 && text[0] == '"' {
+		for i := 1; i < len(text); i++ {
+			switch text[i] {
+			case '\\':
+				i++
+			case '"':
+				return text[:i+1], text[i+1:]
+			}
+		}
+		return text, nil
+	}
+	for i, c := range text {
+		if c == sep || c == '\n' || (sep == '=' && c == ' ') {
+			return text[:i], text[i:]
+		}
+	}
+	return text, nil
+}
+
+// inferKind guesses the slog.Kind of the text rendered value.
+func inferKind(value []byte) slog.Kind {
+	s := string(value)
+	if s == "" {
+		return slog.KindString
+	}
+	if s == "true" || s == "false" || s == "✓" || s == "✗" {
+		return slog.KindBool
+	}
+	if s[0] == '"' {
+		return slog.KindString
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return slog.KindInt64
+	}
+	if _, err := time.ParseDuration(s); err == nil {
+		return slog.KindDuration
+	}
+	if _, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return slog.KindTime
+	}
+	return slog.KindString
+}
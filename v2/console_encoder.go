@@ -0,0 +1,176 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+// AttrEncoder renders the attrs of the records for the ConsoleHandler, as space separated key=value pairs.
+type AttrEncoder interface {
+	// AppendAttrs appends the rendered attrs of the record (and of WithAttrs) to dst.
+	AppendAttrs(dst []byte, r slog.Record) []byte
+	// WithAttrs returns a new AttrEncoder with the attrs pre-set (in the current group).
+	WithAttrs(attrs []slog.Attr) AttrEncoder
+	// WithGroup returns a new AttrEncoder which renders the attrs in the given group.
+	WithGroup(name string) AttrEncoder
+}
+
+var (
+	_ AttrEncoder = textAttrEncoder{}
+	_ AttrEncoder = logfmtAttrEncoder{}
+)
+
+// textAttrEncoder renders the attrs with an slog.TextHandler (this is what the ConsoleHandler does by default).
+type textAttrEncoder struct {
+	h   slog.Handler
+	buf *bytes.Buffer
+	mu  *sync.Mutex
+}
+
+// NewTextAttrEncoder returns an AttrEncoder using an slog.TextHandler with the given options
+// (as the ConsoleHandler does by default).
+func NewTextAttrEncoder(opts *slog.HandlerOptions) AttrEncoder {
+	var o slog.HandlerOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.AddSource = false
+	replaceAttr := o.ReplaceAttr
+	o.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 {
+			switch a.Key {
+			case "time", "level", "source", "msg":
+				return zeroAttr
+			}
+		}
+		if replaceAttr == nil {
+			return a
+		}
+		return replaceAttr(groups, a)
+	}
+	buf := new(bytes.Buffer)
+	return textAttrEncoder{h: slog.NewTextHandler(buf, &o), buf: buf, mu: new(sync.Mutex)}
+}
+
+func (enc textAttrEncoder) AppendAttrs(dst []byte, r slog.Record) []byte {
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+	enc.buf.Reset()
+	_ = enc.h.Handle(context.Background(), r)
+	return append(dst, bytes.TrimSuffix(enc.buf.Bytes(), []byte{'\n'})...)
+}
+func (enc textAttrEncoder) WithAttrs(attrs []slog.Attr) AttrEncoder {
+	enc.h = enc.h.WithAttrs(attrs)
+	return enc
+}
+func (enc textAttrEncoder) WithGroup(name string) AttrEncoder {
+	enc.h = enc.h.WithGroup(name)
+	return enc
+}
+
+// logfmtAttrEncoder renders the attrs directly, without locking.
+type logfmtAttrEncoder struct {
+	prefix string
+	pre    []byte
+}
+
+// NewLogfmtAttrEncoder returns a lock-free AttrEncoder which renders the attrs directly, in logfmt,
+// the non-basic values normalized like in the ConsoleHandler (empty values dropped, slices bracketed, complex values as JSON).
+func NewLogfmtAttrEncoder() AttrEncoder { return logfmtAttrEncoder{} }
+
+func (enc logfmtAttrEncoder) AppendAttrs(dst []byte, r slog.Record) []byte {
+	start := len(dst)
+	dst = append(dst, enc.pre...)
+	r.Attrs(func(a slog.Attr) bool {
+		dst = appendLogfmtAttr(dst, start, enc.prefix, a)
+		return true
+	})
+	return dst
+}
+func (enc logfmtAttrEncoder) WithAttrs(attrs []slog.Attr) AttrEncoder {
+	pre := append(make([]byte, 0, len(enc.pre)+16*len(attrs)), enc.pre...)
+	for _, a := range attrs {
+		pre = appendLogfmtAttr(pre, 0, enc.prefix, a)
+	}
+	return logfmtAttrEncoder{prefix: enc.prefix, pre: pre}
+}
+func (enc logfmtAttrEncoder) WithGroup(name string) AttrEncoder {
+	if name == "" {
+		return enc
+	}
+	return logfmtAttrEncoder{prefix: enc.prefix + name + ".", pre: enc.pre}
+}
+
+// appendLogfmtAttr appends " key=value" (no space at start), groups flattened with "." separated keys.
+func appendLogfmtAttr(dst []byte, start int, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, g := range a.Value.Group() {
+			dst = appendLogfmtAttr(dst, start, prefix, g)
+		}
+		return dst
+	}
+	if a.Key == "" || (!consoleSliceValue(&a.Value) && ensurePrintableValueIsEmpty(&a.Value)) {
+		return dst
+	}
+	if len(dst) > start {
+		dst = append(dst, ' ')
+	}
+	dst = appendLogfmtString(dst, prefix+a.Key)
+	dst = append(dst, '=')
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return appendLogfmtString(dst, v.String())
+	case slog.KindInt64:
+		return strconv.AppendInt(dst, v.Int64(), 10)
+	case slog.KindUint64:
+		return strconv.AppendUint(dst, v.Uint64(), 10)
+	case slog.KindFloat64:
+		return strconv.AppendFloat(dst, v.Float64(), 'g', -1, 64)
+	case slog.KindBool:
+		return strconv.AppendBool(dst, v.Bool())
+	case slog.KindDuration:
+		return append(dst, v.Duration().String()...)
+	case slog.KindTime:
+		return v.Time().AppendFormat(dst, time.RFC3339Nano)
+	default:
+		return appendLogfmtString(dst, v.String())
+	}
+}
+
+// appendLogfmtString appends s, quoted if needed (empty, or has space, '=', '"' or non-printable characters).
+func appendLogfmtString(dst []byte, s string) []byte {
+	if s == "" {
+		return append(dst, `""`...)
+	}
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b <= ' ' || b == '=' || b == '"' || b == 0x7f {
+				return strconv.AppendQuote(dst, s)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || !strconv.IsPrint(r) {
+			return strconv.AppendQuote(dst, s)
+		}
+		i += size
+	}
+	return append(dst, s...)
+}
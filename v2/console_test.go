@@ -1,10 +1,20 @@
 package zlog_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/UNO-SOFT/zlog/v2/slog"
 )
 
 func TestConsole(t *testing.T) {
@@ -27,3 +37,678 @@ func TestConsoleWithEmptyAttrs(t *testing.T) {
 	logger.Info("two empty attrs, but nothing else", "", "", "", "")
 	logger.Info("three empty attrs, plus one", "", "", "", "", "", "", "one", 1)
 }
+
+func TestConsoleLineEnding(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.LineEnding = "\r\n"
+	logger := zlog.NewLogger(h)
+	logger.Info("no attrs")
+	logger.Info("attrs", "a", 1)
+	t.Log(buf.String())
+	if got := bytes.Count(buf.Bytes(), []byte("\r\n")); got != 2 {
+		t.Errorf("got %d CRLF, wanted 2", got)
+	}
+	if got := bytes.Count(buf.Bytes(), []byte("\n")); got != 2 {
+		t.Errorf("got %d LF, wanted 2", got)
+	}
+}
+
+func TestConsoleFlatten(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = false
+	logger := zlog.NewLogger(h)
+	logger.Info("flat", zlog.Flatten("meta", map[any]any{
+		"b": 2, 1: "one", "nested": map[string]int{"x": 3},
+	}))
+	t.Log(buf.String())
+	if got, want := buf.String(), `"flat" meta.1=one meta.b=2 meta.nested.x=3`; !strings.Contains(got, want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestConsoleAttrsBeforeMessage(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = false
+	h.AttrsBeforeMessage = true
+	zlog.NewLogger(h).Info("message", "a", 1, "b", "c")
+	t.Log(buf.String())
+	if got, want := buf.String(), ` a=1 b=c "message"`+"\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, wanted suffix %q", got, want)
+	}
+}
+
+func TestConsoleLevelColorRace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewConsoleHandler(zlog.InfoLevel, &buf))
+	defer zlog.SetLevelColor("INF", zlog.Blue)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			zlog.SetLevelColor("INF", zlog.Color(30+i%8))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			logger.Info("concurrent", "i", i)
+		}
+	}()
+	wg.Wait()
+	if got := bytes.Count(buf.Bytes(), []byte("concurrent")); got != 100 {
+		t.Errorf("got %d lines, wanted 100", got)
+	}
+}
+
+func TestConsoleMultilineDetail(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = false
+	h.MultilineDetail = true
+	zlog.NewLogger(h).Info("first line\nsecond line\nthird", "a", 1)
+	t.Log(buf.String())
+	if got, want := buf.String(), `"first line" a=1 detail="second line\nthird"`+"\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, wanted suffix %q", got, want)
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) { return 0, io.ErrClosedPipe }
+
+func TestConsoleFallback(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, errWriter{})
+	fallback := zlog.NewConsoleHandler(zlog.InfoLevel, errWriter{})
+	fallback.Fallback = h // would loop, if not guarded
+	h.Fallback = zlog.NewMultiHandler(fallback, slog.NewJSONHandler(&buf, nil))
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelError, "critical", 0)); err != nil {
+		t.Log(err)
+	}
+	t.Log(buf.String())
+	if !strings.Contains(buf.String(), `"critical"`) {
+		t.Errorf("got %q, wanted the record in the fallback", buf.String())
+	}
+}
+
+func TestConsoleAttrColors(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = true
+	h.AttrColors = &zlog.DefaultAttrColors
+	zlog.NewLogger(h).Info("colors", "n", 1, "s", "a b", "b", true, "d", time.Second, "error", "bad")
+	t.Log(buf.String())
+	for _, want := range []string{
+		"n=" + zlog.Cyan.Add("1"),
+		"s=" + zlog.Green.Add(`"a b"`),
+		"b=" + zlog.Yellow.Add("true"),
+		"d=" + zlog.Magenta.Add("1s"),
+		"error=" + zlog.Red.Add("bad"),
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%q not in %q", want, buf.String())
+		}
+	}
+}
+
+func TestConsoleEmptyJSONDropped(t *testing.T) {
+	type pair struct{ A, B int }
+	args := []any{"l", []pair{}, "m", map[string]pair{}, "p", pair{A: 1}}
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = false
+	zlog.NewLogger(h).Info("empty", args...)
+	t.Log(buf.String())
+	if got, want := buf.String(), `"empty" p="{\"A\":1,\"B\":0}"`+"\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, wanted suffix %q", got, want)
+	}
+
+	buf.Reset()
+	zlog.NewLogger(zlog.DefaultHandlerOptions.NewJSONHandler(&buf)).Info("empty", args...)
+	t.Log(buf.String())
+	if got, want := buf.String(), `"msg":"empty","p":"{\"A\":1,\"B\":0}"}`; !strings.Contains(got, want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+type emptyStringer struct{}
+
+func (emptyStringer) String() string { return "" }
+
+type emptyName string
+
+func TestKeepEmpty(t *testing.T) {
+	args := []any{"s", any(""), "n", nil, "l", []int{}, "m", map[string]int{}, "str", emptyStringer{}, "name", emptyName("")}
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = false
+	h.KeepEmpty = true
+	zlog.NewLogger(h).Info("empty", args...)
+	t.Log(buf.String())
+	if got, want := buf.String(), `"empty" s="" n=<nil> l=[] m={} str="" name=""`+"\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, wanted suffix %q", got, want)
+	}
+
+	buf.Reset()
+	opts := zlog.DefaultHandlerOptions
+	opts.KeepEmpty = true
+	zlog.NewLogger(opts.NewJSONHandler(&buf)).Info("empty", args...)
+	t.Log(buf.String())
+	if got, want := buf.String(), `"s":"","n":null,"l":[],"m":{},"str":"","name":""}`; !strings.Contains(got, want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestConsoleBoolGlyphs(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = false
+	h.BoolGlyphs = true
+	zlog.NewLogger(h).Info("status", "ready", true, "failed", false, "s", "true")
+	t.Log(buf.String())
+	if got, want := buf.String(), `"status" ready=✓ failed=✗ s=true`+"\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, wanted suffix %q", got, want)
+	}
+}
+
+func TestLevelNum(t *testing.T) {
+	var buf bytes.Buffer
+	opts := zlog.DefaultHandlerOptions
+	opts.LevelNum = true
+	opts.Level = zlog.TraceLevel
+	logger := zlog.NewLogger(opts.NewJSONHandler(&buf))
+	logger.LogAttrs(context.Background(), zlog.TraceLevel, "trace")
+	logger.Info("info")
+	logger.Audit("audit")
+	t.Log(buf.String())
+	for i, want := range []string{
+		`"level":"DEBUG-1","level_num":-5,`,
+		`"level":"INFO","level_num":0,`,
+		`"level":"AUDIT","level_num":12,`,
+	} {
+		if line := strings.Split(buf.String(), "\n")[i]; !strings.Contains(line, want) {
+			t.Errorf("%d. got %s, wanted %s", i, line, want)
+		}
+	}
+
+	// the user's ReplaceAttr sees the level once, and does not see level_num
+	var levels, levelNums int
+	opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		switch a.Key {
+		case "level":
+			levels++
+			if _, ok := a.Value.Any().(slog.Level); !ok {
+				t.Errorf("got level %v (%T), wanted a slog.Level", a.Value, a.Value.Any())
+			}
+		case "level_num":
+			levelNums++
+		}
+		return a
+	}
+	buf.Reset()
+	logger = zlog.NewLogger(opts.NewJSONHandler(&buf))
+	logger.Info("info")
+	logger.Warn("warn")
+	t.Log(buf.String())
+	if levels != 2 || levelNums != 0 {
+		t.Errorf("ReplaceAttr got %d levels and %d level_nums, wanted 2 and 0", levels, levelNums)
+	}
+	if got := buf.String(); !strings.Contains(got, `"level":"INFO","level_num":0,`) || !strings.Contains(got, `"level":"WARN","level_num":4,`) {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestConsoleGroupPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = false
+	h.GroupPrefix = true
+	logger := zlog.NewLogger(h)
+	logger.Info("naked")
+	logger.WithGroup("http").WithGroup("server").Info("request received", "a", 1)
+	t.Log(buf.String())
+	lines := strings.Split(buf.String(), "\n")
+	if got, want := lines[0], ` "naked"`; !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, wanted suffix %q", got, want)
+	}
+	if got, want := lines[1], ` "[http.server] request received" http.server.a=1`; !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, wanted suffix %q", got, want)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	loc := time.FixedZone("X", 3*3600+1800)
+	tm := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	opts := zlog.DefaultHandlerOptions
+	opts.Location = loc
+
+	var buf bytes.Buffer
+	if err := opts.MaybeConsoleHandler(&buf).Handle(context.Background(), slog.NewRecord(tm, slog.LevelInfo, "json", 0)); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+	if want := `"time":"2024-01-02T13:30:00+03:30"`; !strings.Contains(buf.String(), want) {
+		t.Errorf("got %q, wanted %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.Location = loc
+	if err := h.Handle(context.Background(), slog.NewRecord(tm, slog.LevelInfo, "console", 0)); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+	if want := "13:30:00.000 "; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("got %q, wanted prefix %q", buf.String(), want)
+	}
+}
+
+func TestConsoleDiffAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = true
+	h.DiffAttrs = zlog.Yellow
+	logger := zlog.NewLogger(h).WithValues("id", 1)
+	logger.Info("state", "a", 1, "b", 2)
+	logger.Info("state", "a", 1, "b", 3)
+	t.Log(buf.String())
+	lines := strings.Split(buf.String(), "\n")
+	if want := zlog.Yellow.Add("id=1") + " " + zlog.Yellow.Add("a=1") + " " + zlog.Yellow.Add("b=2"); !strings.HasSuffix(lines[0], want) {
+		t.Errorf("got %q, wanted suffix %q", lines[0], want)
+	}
+	if want := " id=1 a=1 " + zlog.Yellow.Add("b=3"); !strings.HasSuffix(lines[1], want) {
+		t.Errorf("got %q, wanted suffix %q", lines[1], want)
+	}
+}
+
+func TestConsoleFormatters(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = false
+	h.Formatters = map[string]func(slog.Value) string{
+		"latency_ms": func(v slog.Value) string { return v.String() + "ms" },
+		"bytes":      func(v slog.Value) string { return strconv.FormatInt(v.Int64()>>10, 10) + "KiB" },
+	}
+	zlog.NewLogger(h).WithValues("bytes", 4096).Info("done", "latency_ms", 12, "other", 1)
+	t.Log(buf.String())
+	if got, want := buf.String(), `"done" bytes=4KiB latency_ms=12ms other=1`+"\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, wanted suffix %q", got, want)
+	}
+}
+
+func TestSourceLevel(t *testing.T) {
+	var bufJSON, bufConsole bytes.Buffer
+	opts := zlog.DefaultHandlerOptions
+	opts.SourceLevel = slog.LevelWarn
+	ch := zlog.NewConsoleHandler(zlog.InfoLevel, &bufConsole)
+	ch.UseColor = false
+	ch.AddSource = true
+	ch.SourceLevel = slog.LevelWarn
+	logger := zlog.NewLogger(zlog.NewMultiHandler(opts.NewJSONHandler(&bufJSON), ch))
+	logger.Info("info")
+	logger.Warn("warn")
+	t.Log(bufJSON.String())
+	t.Log(bufConsole.String())
+	for name, buf := range map[string]*bytes.Buffer{"json": &bufJSON, "console": &bufConsole} {
+		lines := strings.Split(buf.String(), "\n")
+		if strings.Contains(lines[0], "console_test.go:") {
+			t.Errorf("%s: source on info: %q", name, lines[0])
+		}
+		if !strings.Contains(lines[1], "console_test.go:") {
+			t.Errorf("%s: no source on warn: %q", name, lines[1])
+		}
+	}
+}
+
+func TestConsoleIndentJSON(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = false
+	h.IndentJSON = 20
+	cfg := map[string]any{"name": "server", "port": 8080, "tags": []string{"a", "b"}}
+	zlog.NewLogger(h).Info("config", "cfg", cfg, "small", []int{1}, "n", 1)
+	t.Log(buf.String())
+	want := `"config" small=[1] n=1
+  cfg={
+    "name": "server",
+    "port": 8080,
+    "tags": [
+      "a",
+      "b"
+    ]
+  }
+`
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, wanted suffix %q", got, want)
+	}
+}
+
+type consoleMoney struct{ cents int }
+
+func (m consoleMoney) ConsoleString() string {
+	return fmt.Sprintf("$%d.%02d", m.cents/100, m.cents%100)
+}
+func (m consoleMoney) LogValue() slog.Value { return slog.IntValue(m.cents) }
+
+func TestConsoleValuer(t *testing.T) {
+	var bufConsole, bufJSON bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &bufConsole)
+	h.UseColor = false
+	logger := zlog.NewLogger(zlog.NewMultiHandler(h, slog.NewJSONHandler(&bufJSON, nil)))
+	logger.WithValues("base", consoleMoney{100}).Info("price", "amount", consoleMoney{1234})
+	t.Log(bufConsole.String())
+	t.Log(bufJSON.String())
+	if got, want := bufConsole.String(), `"price" base=$1.00 amount=$12.34`+"\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, wanted suffix %q", got, want)
+	}
+	if got, want := bufJSON.String(), `"base":100,"amount":1234}`; !strings.Contains(got, want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestConsoleColorizeMessage(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = true
+	h.AttrColors = &zlog.DefaultAttrColors
+	h.ColorizeMessage = true
+	logger := zlog.NewLogger(h)
+	logger.Info("op=create id=5 ok")
+	logger.Info("plain message")
+	t.Log(buf.String())
+	lines := strings.Split(buf.String(), "\n")
+	if want := " op=" + zlog.Green.Add("create") + " id=" + zlog.Cyan.Add("5") + " ok"; !strings.HasSuffix(lines[0], want) {
+		t.Errorf("got %q, wanted suffix %q", lines[0], want)
+	}
+	if want := ` "plain message"`; !strings.HasSuffix(lines[1], want) {
+		t.Errorf("got %q, wanted suffix %q", lines[1], want)
+	}
+}
+
+func TestConsoleAttrEncoder(t *testing.T) {
+	for name, enc := range map[string]zlog.AttrEncoder{
+		"text":   zlog.NewTextAttrEncoder(nil),
+		"logfmt": zlog.NewLogfmtAttrEncoder(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+			h.AttrEncoder = enc
+			logger := zlog.NewLogger(h).SLog().With("a", 1).WithGroup("g").With("b", "x y")
+			logger.Info("msg", "c", true, "d", 1.5)
+			t.Log(buf.String())
+			if want := ` "msg" a=1 g.b="x y" g.c=true g.d=1.5` + "\n"; !strings.HasSuffix(buf.String(), want) {
+				t.Errorf("got %q, wanted suffix %q", buf.String(), want)
+			}
+		})
+	}
+}
+
+func BenchmarkConsoleAttrEncoder(b *testing.B) {
+	for name, enc := range map[string]zlog.AttrEncoder{
+		"default": nil,
+		"text":    zlog.NewTextAttrEncoder(nil),
+		"logfmt":  zlog.NewLogfmtAttrEncoder(),
+	} {
+		b.Run(name, func(b *testing.B) {
+			h := zlog.NewConsoleHandler(zlog.InfoLevel, io.Discard)
+			h.AttrEncoder = enc
+			logger := zlog.NewLogger(h).SLog().With("app", "bench")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logger.Info("message", "i", i, "s", "some string", "ok", true)
+			}
+		})
+	}
+}
+
+func TestConsoleFormatMessage(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.FormatMessage = func(level slog.Level, msg string) string {
+		if level >= slog.LevelError {
+			return "🔥 " + msg
+		}
+		return msg
+	}
+	logger := zlog.NewLogger(h)
+	logger.Info("calm")
+	logger.Error(errors.New("x"), "burning")
+	t.Log(buf.String())
+	lines := strings.Split(buf.String(), "\n")
+	if want := ` "calm"`; !strings.HasSuffix(lines[0], want) {
+		t.Errorf("got %q, wanted suffix %q", lines[0], want)
+	}
+	if want := ` "🔥 burning" error=x`; !strings.HasSuffix(lines[1], want) {
+		t.Errorf("got %q, wanted suffix %q", lines[1], want)
+	}
+}
+
+func TestConsoleSourceWidth(t *testing.T) {
+	for _, width := range []int{40, -40} {
+		var buf bytes.Buffer
+		h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+		h.AddSource = true
+		h.SourceWidth = width
+		logger := zlog.NewLogger(h)
+		logger.Info("first")
+		func() { logger.Info("second") }()
+		t.Log(buf.String())
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("got %d lines, wanted 2", len(lines))
+		}
+		if i, j := strings.Index(lines[0], `"first"`), strings.Index(lines[1], `"second"`); i != j {
+			t.Errorf("%d: messages start at %d and %d", width, i, j)
+		}
+	}
+}
+
+func TestConsoleSlices(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewConsoleHandler(zlog.InfoLevel, &buf))
+	long := make([]int, 40)
+	logger.Info("slices", "ids", []int{1, 2, 3}, "nested", [][]string{{"a", "b"}, {"c"}},
+		"any", []any{1, "x", nil}, "long", long, "empty", []int{})
+	t.Log(buf.String())
+	want := ` ids=[1,2,3] nested=[[a,b],[c]] any=[1,x,<nil>] long=[` + strings.Repeat("0,", 32) + "…]\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("got %q, wanted suffix %q", buf.String(), want)
+	}
+}
+
+type cyclic struct {
+	Name string
+	Next *cyclic
+}
+
+type selfNode struct {
+	Name    string
+	A, B, C *selfNode
+}
+
+func TestConsoleSelfReferencingValue(t *testing.T) {
+	n := &selfNode{Name: "n"}
+	n.A, n.B, n.C = n, n, n
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewConsoleHandler(zlog.InfoLevel, &buf))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Info("self", "n", n)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging a self-referencing value hangs")
+	}
+	t.Log(buf.String())
+	if got, want := buf.String(), `n="{\"A\":\"…\",\"B\":\"…\",\"C\":\"…\",\"Name\":\"n\"}"`; !strings.Contains(got, want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	// a wide, shared (not cyclic) graph is bounded by MaxJSONNodes
+	leaf := &selfNode{Name: "leaf"}
+	for i := 0; i < 64; i++ {
+		leaf = &selfNode{Name: strconv.Itoa(i), A: leaf, B: leaf, C: leaf}
+	}
+	buf.Reset()
+	start := time.Now()
+	logger.Info("shared", "n", leaf)
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("logging a shared graph took %s", d)
+	}
+	if !strings.Contains(buf.String(), "…") {
+		t.Errorf("got %q, wanted a truncated JSON", buf.String())
+	}
+}
+
+func TestConsoleCyclicValue(t *testing.T) {
+	c := &cyclic{Name: "a"}
+	c.Next = &cyclic{Name: "b", Next: c}
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewConsoleHandler(zlog.InfoLevel, &buf))
+	logger.Info("cycle", "c", c)
+	t.Log(buf.String())
+	if got, want := buf.String(), `c="{\"Name\":\"a\",\"Next\":{\"Name\":\"b\",\"Next\":\"…\"}}"`; !strings.Contains(got, want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	var self any
+	self = &self
+	buf.Reset()
+	logger.Info("self", "p", self)
+	if !strings.Contains(buf.String(), `p="\"…\""`) {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+type sharedLeaf struct{ N int }
+
+type sharedPair struct{ A, B *sharedLeaf }
+
+type jsonBase struct{ ID int }
+
+type jsonPoint struct{ X, Y int }
+
+func (p jsonPoint) MarshalText() ([]byte, error) { return fmt.Appendf(nil, "%d,%d", p.X, p.Y), nil }
+
+type jsonShape struct {
+	jsonBase
+	Opt  string `json:"opt,omitempty"`
+	Raw  []byte
+	At   jsonPoint
+	Self *jsonShape
+}
+
+func TestConsoleSharedValue(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = false
+	logger := zlog.NewLogger(h)
+	leaf := &sharedLeaf{N: 1}
+	logger.Info("shared", "p", sharedPair{A: leaf, B: leaf})
+	t.Log(buf.String())
+	if got, want := buf.String(), `p="{\"A\":{\"N\":1},\"B\":{\"N\":1}}"`; !strings.Contains(got, want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	// the truncated value is encoded as encoding/json would do
+	s := &jsonShape{jsonBase: jsonBase{ID: 7}, Raw: []byte("hi"), At: jsonPoint{X: 1, Y: 2}}
+	s.Self = s
+	buf.Reset()
+	logger.Info("cycle", "s", s)
+	t.Log(buf.String())
+	if got, want := buf.String(), `s="{\"At\":\"1,2\",\"ID\":7,\"Raw\":\"aGk=\",\"Self\":\"…\"}"`; !strings.Contains(got, want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestConsoleWholeSecondTime(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = false
+	for _, tm := range []time.Time{
+		time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local),
+		time.Date(2024, 1, 2, 15, 4, 5, 100_000_000, time.Local),
+		time.Date(2024, 1, 2, 15, 4, 5, 120_000_000, time.Local),
+	} {
+		if err := h.Handle(context.Background(), slog.NewRecord(tm, slog.LevelInfo, "at", 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Log(buf.String())
+	if got, want := buf.String(), "15:04:05.000 INF \"at\"\n15:04:05.100 INF \"at\"\n15:04:05.120 INF \"at\"\n"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestConsoleTimeAttrFormat(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.TimeAttrFormat = time.Kitchen
+	logger := zlog.NewLogger(h)
+	logger.Info("at", "deadline", time.Date(2024, 1, 2, 15, 4, 0, 0, time.Local))
+	h.TimeAttrFormat = zlog.RelativeTimeFormat
+	logger.Info("rel", "deadline", time.Now().Add(time.Hour))
+	t.Log(buf.String())
+	lines := strings.Split(buf.String(), "\n")
+	if want := ` deadline=3:04PM`; !strings.HasSuffix(lines[0], want) {
+		t.Errorf("got %q, wanted suffix %q", lines[0], want)
+	}
+	if want := ` deadline="in 1h0m0s"`; !strings.HasSuffix(lines[1], want) {
+		t.Errorf("got %q, wanted %q", lines[1], want)
+	}
+}
+
+type nilStringer struct{ s string }
+
+func (ns *nilStringer) String() string { return ns.s }
+
+func TestConsoleNil(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewConsoleHandler(zlog.InfoLevel, &buf)
+	h.UseColor = false
+	logger := zlog.NewLogger(h)
+	var jsonBuf bytes.Buffer
+	jsonLogger := zlog.NewLogger(zlog.DefaultHandlerOptions.NewJSONHandler(&jsonBuf))
+	var err error
+	for _, tc := range []struct {
+		Name      string
+		Value     any
+		Want      string
+		WantJSON  string
+		WantEmpty bool
+	}{
+		{Name: "nil", Value: nil, Want: "x=<nil>", WantJSON: `"x":null`},
+		{Name: "nil error", Value: err, Want: "x=<nil>", WantJSON: `"x":null`},
+		{Name: "typed nil pointer", Value: (*cyclic)(nil), Want: "x=<nil>", WantJSON: `"x":null`},
+		{Name: "typed nil Stringer", Value: (*nilStringer)(nil), Want: "x=<nil>", WantJSON: `"x":null`},
+		{Name: "typed nil error", Value: (*fs.PathError)(nil), Want: "x=<nil>", WantJSON: `"x":null`},
+		{Name: "nil slice", Value: []int(nil), WantEmpty: true},
+		{Name: "nil map", Value: map[string]int(nil), WantEmpty: true},
+	} {
+		buf.Reset()
+		jsonBuf.Reset()
+		logger.Info("nil", "x", tc.Value)
+		jsonLogger.Info("nil", "x", tc.Value)
+		got, gotJSON := strings.TrimSpace(buf.String()), jsonBuf.String()
+		if tc.WantEmpty {
+			if strings.Contains(got, "x=") || strings.Contains(gotJSON, `"x"`) {
+				t.Errorf("%s: got %q and %q, wanted dropped", tc.Name, got, gotJSON)
+			}
+			continue
+		}
+		if !strings.HasSuffix(got, tc.Want) {
+			t.Errorf("%s: got %q, wanted suffix %q", tc.Name, got, tc.Want)
+		}
+		if !strings.Contains(gotJSON, tc.WantJSON) {
+			t.Errorf("%s: got %q, wanted %q", tc.Name, gotJSON, tc.WantJSON)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"strings"
+	"unicode"
+)
+
+// displayWidth returns the display width of s in terminal cells.
+//
+// The default is a dependency-free approximation (combining marks are zero width,
+// East Asian wide and fullwidth characters and emojis are two cells wide);
+// build with the "runewidth" tag to use github.com/mattn/go-runewidth instead.
+//
+// The "runewidth" tag is opt-in: this module does not require go-runewidth,
+// so add it to your own module (go get github.com/mattn/go-runewidth) before building with it.
+var displayWidth = approxDisplayWidth
+
+func approxDisplayWidth(s string) int {
+	var n int
+	for _, r := range s {
+		switch {
+		case r < 0x20 || r == 0x7f:
+		case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || r == 0x200b:
+		case isWideRune(r):
+			n += 2
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// isWideRune reports whether r is (most probably) an East Asian Wide or Fullwidth character.
+func isWideRune(r rune) bool {
+	return r >= 0x1100 && (r <= 0x115f || // Hangul Jamo
+		r == 0x2329 || r == 0x232a ||
+		(r >= 0x2e80 && r <= 0xa4cf && r != 0x303f) || // CJK ... Yi
+		(r >= 0xac00 && r <= 0xd7a3) || // Hangul Syllables
+		(r >= 0xf900 && r <= 0xfaff) || // CJK Compatibility Ideographs
+		(r >= 0xfe30 && r <= 0xfe4f) || // CJK Compatibility Forms
+		(r >= 0xff00 && r <= 0xff60) || // Fullwidth Forms
+		(r >= 0xffe0 && r <= 0xffe6) ||
+		(r >= 0x1f300 && r <= 0x1f64f) || // Misc Symbols and Pictographs, Emoticons
+		(r >= 0x1f900 && r <= 0x1f9ff) || // Supplemental Symbols and Pictographs
+		(r >= 0x20000 && r <= 0x3fffd))
+}
+
+// padRight pads s with spaces to the given display width.
+func padRight(s string, width int) string {
+	if n := width - displayWidth(s); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+	return s
+}
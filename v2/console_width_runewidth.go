@@ -0,0 +1,14 @@
+//go:build runewidth
+
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+// This file is built only with the opt-in "runewidth" tag,
+// which needs github.com/mattn/go-runewidth in the main module's go.mod.
+
+import "github.com/mattn/go-runewidth"
+
+func init() { displayWidth = runewidth.StringWidth }
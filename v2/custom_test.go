@@ -1,6 +1,10 @@
 package zlog
 
 import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/UNO-SOFT/zlog/v2/slog"
@@ -13,3 +17,81 @@ func TestCustomSource(t *testing.T) {
 	logger.Debug("Debug")
 	logger.Info("no attrs")
 }
+
+func TestShouldUseColor(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "color-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, tc := range []struct {
+		Name string
+		Env  map[string]string
+		File bool
+		Want bool
+	}{
+		{Name: "buffer", Want: true},
+		{Name: "file", File: true, Want: false},
+		{Name: "NO_COLOR", Env: map[string]string{"NO_COLOR": ""}, Want: false},
+		{Name: "dumb", Env: map[string]string{"TERM": "dumb"}, Want: false},
+		{Name: "FORCE_COLOR", Env: map[string]string{"FORCE_COLOR": "1", "TERM": "dumb"}, File: true, Want: true},
+		{Name: "git pager", Env: map[string]string{"GIT_PAGER_IN_USE": "true"}, File: true, Want: true},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			for _, k := range []string{"NO_COLOR", "FORCE_COLOR", "TERM", "GIT_PAGER_IN_USE"} {
+				t.Setenv(k, "")
+				os.Unsetenv(k)
+			}
+			for k, v := range tc.Env {
+				t.Setenv(k, v)
+			}
+			var w io.Writer = new(bytes.Buffer)
+			if tc.File {
+				w = f
+			}
+			if got := shouldUseColor(w); got != tc.Want {
+				t.Errorf("got %t, wanted %t", got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	for _, tc := range []struct {
+		S    string
+		Want int
+	}{
+		{"abc", 3},
+		{"árvíztűrő", 9},
+		{"日本語", 6},
+		{"ｱｲｳ", 3},
+		{"é", 1},
+	} {
+		if got := displayWidth(tc.S); got != tc.Want {
+			t.Errorf("%q: got %d, wanted %d", tc.S, got, tc.Want)
+		}
+		if got := displayWidth(padRight(tc.S, 10)); got != 10 {
+			t.Errorf("%q: padded to %d, wanted 10", tc.S, got)
+		}
+	}
+}
+
+func TestMust(t *testing.T) {
+	var code int
+	defer func(old func(int)) { exit = old }(exit)
+	exit = func(c int) { code = c }
+
+	var buf bytes.Buffer
+	logger := NewLogger(DefaultHandlerOptions.NewJSONHandler(&buf))
+	if got := Must(logger, 1, nil); got != 1 || buf.Len() != 0 || code != 0 {
+		t.Errorf("got %d, %q, %d", got, buf.String(), code)
+	}
+	Must(logger, 0, io.ErrUnexpectedEOF)
+	t.Log(buf.String())
+	if code != 1 {
+		t.Errorf("exit code: got %d, wanted 1", code)
+	}
+	if s := buf.String(); !strings.Contains(s, "custom_test.go:") || !strings.Contains(s, `"error":"unexpected EOF"`) {
+		t.Errorf("got %s, wanted the source of the call site and the error", s)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+// exit is os.Exit, replaceable for tests.
+var exit = os.Exit
+
+// Fatal logs the error at ErrorLevel (regardless of the level), flushes the handlers and exits with 1.
+func (lgr Logger) Fatal(err error, msg string, args ...any) { lgr.fatal(err, msg, args...) }
+
+// Must returns v if err is nil, otherwise logs the error (with the source of the caller of Must) and exits (see Logger.Fatal).
+//
+//	cfg := zlog.Must(logger, loadConfig(path))
+func Must[T any](lgr Logger, v T, err error) T {
+	if err != nil {
+		lgr.fatal(err, "must")
+	}
+	return v
+}
+
+func (lgr Logger) fatal(err error, msg string, args ...any) {
+	var pcs [1]uintptr
+	// skip [runtime.Callers, this function, Fatal/Must]
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), slog.LevelError, msg, pcs[0])
+	r.Add(args...)
+	r.AddAttrs(errorAttr(err))
+	ctx := context.Background()
+	_ = lgr.load().Handler().Handle(ctx, r)
+	_ = lgr.Flush(ctx)
+	exit(1)
+}
+
+// PanicKey and StackKey are the keys of the panic value and the stack logged by Catch.
+var (
+	PanicKey = "panic"
+	StackKey = "stack"
+)
+
+// CatchStackDepth is the maximum number of stack frames logged by Catch.
+var CatchStackDepth = 32
+
+// Catch recovers the panic (if any), and logs it with the (trimmed) stack at ErrorLevel,
+// re-panicking with the same value if rethrow is true.
+//
+// Catch must be deferred directly, at goroutine boundaries for example:
+//
+//	go func() {
+//		defer logger.Catch(false)
+//		...
+//	}()
+func (lgr Logger) Catch(rethrow bool) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	depth := CatchStackDepth
+	if depth <= 0 {
+		depth = 1
+	}
+	pcs := make([]uintptr, depth+8)
+	// skip [runtime.Callers, Catch]
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	var pc uintptr
+	stack := make([]string, 0, depth)
+	frames := runtime.CallersFrames(pcs)
+	for len(stack) < depth {
+		frame, more := frames.Next()
+		// skip the panic machinery
+		if pc != 0 || !strings.HasPrefix(frame.Function, "runtime.") {
+			if pc == 0 {
+				pc = frame.PC
+			}
+			stack = append(stack, frame.Function+" "+trimRootPath(frame.File)+":"+strconv.Itoa(frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	r := slog.NewRecord(time.Now(), slog.LevelError, "panic", pc)
+	r.AddAttrs(slog.Any(PanicKey, p), slog.Any(StackKey, stack))
+	ctx := context.Background()
+	_ = lgr.load().Handler().Handle(ctx, r)
+	if rethrow {
+		panic(p)
+	}
+}
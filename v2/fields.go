@@ -0,0 +1,71 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+// Fields accumulates attrs (like zerolog's event builder), to be logged at once:
+//
+//	var f zlog.Fields
+//	f.Str("k", "v").Int("n", 1)
+//	logger.Info("msg", f.Attrs()...)
+type Fields struct{ attrs []slog.Attr }
+
+// Attrs returns the accumulated attrs as args for the ...any logging methods.
+func (f *Fields) Attrs() []any {
+	args := make([]any, len(f.attrs))
+	for i, a := range f.attrs {
+		args[i] = a
+	}
+	return args
+}
+
+// SlogAttrs returns the accumulated attrs, for the *Attrs logging methods.
+func (f *Fields) SlogAttrs() []slog.Attr { return f.attrs }
+
+// Len returns the number of the accumulated attrs.
+func (f *Fields) Len() int { return len(f.attrs) }
+
+// Attr adds the attr.
+func (f *Fields) Attr(a slog.Attr) *Fields { f.attrs = append(f.attrs, a); return f }
+
+// Str adds a string attr.
+func (f *Fields) Str(k, v string) *Fields { return f.Attr(slog.String(k, v)) }
+
+// Int adds an int attr.
+func (f *Fields) Int(k string, v int) *Fields { return f.Attr(slog.Int(k, v)) }
+
+// Int64 adds an int64 attr.
+func (f *Fields) Int64(k string, v int64) *Fields { return f.Attr(slog.Int64(k, v)) }
+
+// Uint64 adds an uint64 attr.
+func (f *Fields) Uint64(k string, v uint64) *Fields { return f.Attr(slog.Uint64(k, v)) }
+
+// Float64 adds a float64 attr.
+func (f *Fields) Float64(k string, v float64) *Fields { return f.Attr(slog.Float64(k, v)) }
+
+// Bool adds a bool attr.
+func (f *Fields) Bool(k string, v bool) *Fields { return f.Attr(slog.Bool(k, v)) }
+
+// Dur adds a time.Duration attr.
+func (f *Fields) Dur(k string, v time.Duration) *Fields { return f.Attr(slog.Duration(k, v)) }
+
+// Time adds a time.Time attr.
+func (f *Fields) Time(k string, v time.Time) *Fields { return f.Attr(slog.Time(k, v)) }
+
+// Err adds the error as an ErrorKey ("error" by default) attr, if it is not nil.
+func (f *Fields) Err(err error) *Fields {
+	if err == nil {
+		return f
+	}
+	return f.Attr(errorAttr(err))
+}
+
+// Any adds an attr with any value.
+func (f *Fields) Any(k string, v any) *Fields { return f.Attr(slog.Any(k, v)) }
@@ -0,0 +1,100 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"compress/gzip"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultGzipFlushInterval is the default flush interval of the GzipWriter.
+const DefaultGzipFlushInterval = time.Second
+
+var _ = io.WriteCloser((*GzipWriter)(nil))
+
+// GzipWriter compresses the logs written to it on the fly,
+// flushing the compressed stream at most FlushInterval after each Write,
+// so the logs are readable (with zcat) before Close.
+//
+// It is goroutine-safe, so it can be the writer of a handler directly,
+// or the file of a rotating writer (compressing each rotated file).
+type GzipWriter struct {
+	w     io.Writer
+	gz    *gzip.Writer
+	timer *time.Timer
+	// FlushInterval is the maximum delay of flushing the written data (DefaultGzipFlushInterval by default);
+	// if negative, each Write is flushed immediately (worse compression).
+	FlushInterval time.Duration
+	mu            sync.Mutex
+	closed        bool
+}
+
+// NewGzipWriter returns a new GzipWriter writing the compressed stream to w.
+func NewGzipWriter(w io.Writer) *GzipWriter {
+	return &GzipWriter{w: w, gz: gzip.NewWriter(w), FlushInterval: DefaultGzipFlushInterval}
+}
+
+// Write compresses p, scheduling a flush.
+func (gw *GzipWriter) Write(p []byte) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	if gw.closed {
+		return 0, io.ErrClosedPipe
+	}
+	n, err := gw.gz.Write(p)
+	if err != nil {
+		return n, err
+	}
+	if gw.FlushInterval < 0 {
+		return n, gw.gz.Flush()
+	}
+	if gw.timer == nil {
+		d := gw.FlushInterval
+		if d == 0 {
+			d = DefaultGzipFlushInterval
+		}
+		gw.timer = time.AfterFunc(d, func() { _ = gw.Flush() })
+	}
+	return n, nil
+}
+
+// Flush the compressed data written so far to the underlying writer.
+//
+// The flushed stream is a valid (although unterminated) gzip stream, which zcat can decompress.
+func (gw *GzipWriter) Flush() error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	if gw.timer != nil {
+		gw.timer.Stop()
+		gw.timer = nil
+	}
+	if gw.closed {
+		return nil
+	}
+	return gw.gz.Flush()
+}
+
+// Close terminates the gzip stream, and closes the underlying writer, if it is an io.Closer.
+func (gw *GzipWriter) Close() error {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	if gw.closed {
+		return nil
+	}
+	gw.closed = true
+	if gw.timer != nil {
+		gw.timer.Stop()
+		gw.timer = nil
+	}
+	err := gw.gz.Close()
+	if c, ok := gw.w.(io.Closer); ok {
+		if closeErr := c.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
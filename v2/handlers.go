@@ -7,7 +7,9 @@
 package zlog
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io"
 	"sync"
 	"testing"
@@ -35,6 +37,8 @@ const (
 	DebugLevel = slog.LevelDebug
 	InfoLevel  = slog.LevelInfo
 	ErrorLevel = slog.LevelError
+	// AuditLevel is above ErrorLevel, and always passes the level filters of LevelHandler and ConsoleHandler.
+	AuditLevel = slog.LevelError + 4
 )
 
 type testWriter struct {
@@ -51,6 +55,34 @@ func NewT(t testing.TB) Logger {
 	return NewLogger(slog.NewTextHandler(testWriter{T: t}, &slog.HandlerOptions{Level: TraceLevel}))
 }
 
+// CaptureOutput calls f with a Logger writing to a ConsoleHandler (at TraceLevel),
+// and returns the produced output, with colors off and timestamps zeroed,
+// suitable for golden tests.
+func CaptureOutput(f func(Logger)) []byte {
+	var buf bytes.Buffer
+	ch := NewConsoleHandler(TraceLevel, &buf)
+	ch.UseColor = false
+	f(NewLogger(zeroTimeHandler{ch}))
+	return buf.Bytes()
+}
+
+// zeroTimeHandler zeroes the time of the records.
+type zeroTimeHandler struct{ slog.Handler }
+
+func (h zeroTimeHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.Time = time.Time{}
+	return h.Handler.Handle(ctx, r)
+}
+func (h zeroTimeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return zeroTimeHandler{h.Handler.WithAttrs(attrs)}
+}
+func (h zeroTimeHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return zeroTimeHandler{h.Handler.WithGroup(name)}
+}
+
 func (t testWriter) Write(p []byte) (int, error) {
 	t.T.Log(string(p))
 	return len(p), nil
@@ -64,30 +96,171 @@ type SyncWriter struct {
 
 var _ = io.Writer((*SyncWriter)(nil))
 
-// NewSyncWriter returns an io.Writer that syncs each io.Write
-func NewSyncWriter(w io.Writer) *SyncWriter { return &SyncWriter{w: w} }
+// NewSyncWriter returns an io.Writer that syncs each io.Write (to io.Discard if w is nil)
+func NewSyncWriter(w io.Writer) *SyncWriter { return &SyncWriter{w: nonNilWriter(w)} }
 func (sw *SyncWriter) Write(p []byte) (int, error) {
 	sw.mu.Lock()
 	defer sw.mu.Unlock()
 	return sw.w.Write(p)
 }
 
+// framedWriter writes the elements with a header before the first one, a separator between them,
+// and a footer on close: the shared part of JSONArrayWriter, ChromeTraceHandler and HTMLHandler.
+type framedWriter struct {
+	w                   io.Writer
+	header, sep, footer string
+	// empty is written by close if no element has been written
+	empty   string
+	mu      sync.Mutex
+	started bool
+}
+
+// writeElement writes p, preceded by the header (for the first element) or the separator.
+func (fw *framedWriter) writeElement(p []byte) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	prefix := fw.sep
+	if !fw.started {
+		prefix = fw.header
+	}
+	if _, err := fw.w.Write(append([]byte(prefix), p...)); err != nil {
+		return err
+	}
+	fw.started = true
+	return nil
+}
+
+// close writes the footer (or empty, if no element has been written),
+// and starts over: the next element gets the header again.
+func (fw *framedWriter) close() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	end := fw.footer
+	if !fw.started {
+		end = fw.empty
+	}
+	fw.started = false
+	if end == "" {
+		return nil
+	}
+	_, err := io.WriteString(fw.w, end)
+	return err
+}
+
+// JSONArrayWriter writes the JSON objects (one per Write, as the JSON handlers write them)
+// as the elements of one JSON array, across all Writes (and batch flushes): "[\n{...},\n{...}\n]\n".
+//
+// Close writes the closing "]".
+type JSONArrayWriter struct {
+	fw framedWriter
+}
+
+var _ = io.WriteCloser((*JSONArrayWriter)(nil))
+
+// NewJSONArrayWriter returns a new JSONArrayWriter writing to w.
+func NewJSONArrayWriter(w io.Writer) *JSONArrayWriter {
+	return &JSONArrayWriter{fw: framedWriter{w: w, header: "[\n", sep: ",\n", footer: "\n]\n", empty: "[]\n"}}
+}
+
+// Write the JSON object p as the next element of the array.
+func (aw *JSONArrayWriter) Write(p []byte) (int, error) {
+	obj := bytes.TrimRight(p, "\r\n")
+	if len(obj) == 0 {
+		return len(p), nil
+	}
+	if err := aw.fw.writeElement(obj); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes the closing "]" (an empty array if nothing has been written).
+func (aw *JSONArrayWriter) Close() error { return aw.fw.close() }
+
 // NewBatchingHandler returns a BatchingHandler that sends the record to the given Handler
 // periodically (iff interval > 0) or when the backlog is full.
+//
+// The output format is that of the given Handler - JSON lines for a JSON handler;
+// write it through a JSONArrayWriter to get one JSON array instead,
+// or see WithBatchFormat for formatting the whole batch at once.
 func NewBatchingHandler(hndl slog.Handler, interval time.Duration, size int) *batchingHandler {
-	return &batchingHandler{h: hndl, interval: interval, size: size}
+	return &batchingHandler{h: hndl, batch: &batch{interval: interval, size: size, done: make(chan struct{})}}
+}
+
+// TickerFunc returns a channel that ticks periodically, and a function to stop it.
+//
+// The default is based on time.NewTicker.
+type TickerFunc func(time.Duration) (<-chan time.Time, func())
+
+func newTicker(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTicker(d)
+	return t.C, t.Stop
+}
+
+// WithTicker sets the ticker factory used for the periodic flush (for deterministic tests).
+// Must be called before the first Handle.
+func (bh *batchingHandler) WithTicker(f TickerFunc) *batchingHandler {
+	bh.batch.newTicker = f
+	return bh
+}
+
+// WithFlushLevel sets the level at and above which the records flush the backlog synchronously
+// (including the record itself), so an Error is not lost with the preceding records
+// even if the program crashes right after it.
+// Must be called before the first Handle.
+func (bh *batchingHandler) WithFlushLevel(level slog.Leveler) *batchingHandler {
+	bh.batch.flushLevel = level
+	return bh
+}
+
+// WithBatchFormat sets the format of the flushed batch: the records are formatted by format,
+// and written to w with one Write call, instead of being handled by the underlying Handler one by one -
+// for example to wrap them in the envelope of a bulk ingest endpoint ({"streams":[...]} for Loki).
+//
+// The records have the attrs of WithAttrs prepended, and their attrs are in the groups of WithGroup.
+// The underlying Handler is used only for Enabled.
+// Must be called before the first Handle.
+func (bh *batchingHandler) WithBatchFormat(w io.Writer, format func([]slog.Record) ([]byte, error)) *batchingHandler {
+	bh.batch.w, bh.batch.format = w, format
+	return bh
 }
 
 var _ slog.Handler = (*batchingHandler)(nil)
 
+// batchingHandler collects the records into a backlog shared with the handlers derived from it
+// (with WithAttrs/WithGroup), so deriving neither flushes nor starts a new flusher goroutine.
 type batchingHandler struct {
-	h        slog.Handler
+	h slog.Handler
+	*batch
+	// attrs are the attrs of WithAttrs (in their groups), groups are the names of WithGroup - for WithBatchFormat
+	attrs  []slog.Attr
+	groups []string
+}
+
+// batch is the backlog shared by the derived batchingHandlers.
+type batch struct {
 	initOnce sync.Once
-	backlog  []slog.Record
+	backlog  []batchEntry
 	interval time.Duration
 	size     int
+	// newTicker is time.NewTicker, if nil
+	newTicker TickerFunc
+	// flushLevel triggers a flush (iff not nil)
+	flushLevel slog.Leveler
+	// format formats the whole batch to be written to w (iff not nil)
+	format func([]slog.Record) ([]byte, error)
+	w      io.Writer
 	// guards backlog
 	mu sync.Mutex
+	// done stops the periodic flusher
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// batchEntry is a record with the (derived) handler it must be handled by.
+type batchEntry struct {
+	h slog.Handler
+	r slog.Record
 }
 
 // Enabled returns whether the underlying Handler returns Enabled.
@@ -95,46 +268,71 @@ func (bh *batchingHandler) Enabled(ctx context.Context, lvl slog.Level) bool {
 	return bh.h != nil && bh.h.Enabled(ctx, lvl)
 }
 
-// WithAttrs returns a new BatchingHandler with the underlying handlers' attrs set.
-// Implies a Flush.
+// WithAttrs returns a new BatchingHandler with the underlying handlers' attrs set,
+// sharing the backlog with bh.
 func (bh *batchingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	if len(attrs) == 0 {
 		return bh
 	}
-	bh.Flush(context.Background())
-	return NewBatchingHandler(bh.h.WithAttrs(attrs), bh.interval, bh.size)
+	bh2 := *bh
+	bh2.h = bh.h.WithAttrs(attrs)
+	bh2.attrs = append(append(make([]slog.Attr, 0, len(bh.attrs)+len(attrs)), bh.attrs...), inGroups(bh.groups, attrs)...)
+	return &bh2
 }
 
-// WithGroup returns a new BatchingHandler with the underlying handlers' group set.
-// Implies a Flush.
+// WithGroup returns a new BatchingHandler with the underlying handlers' group set,
+// sharing the backlog with bh.
 func (bh *batchingHandler) WithGroup(name string) slog.Handler {
 	if name == "" {
 		return bh
 	}
-	bh.Flush(context.Background())
-	return NewBatchingHandler(bh.h.WithGroup(name), bh.interval, bh.size)
+	bh2 := *bh
+	bh2.h = bh.h.WithGroup(name)
+	bh2.groups = append(append(make([]string, 0, len(bh.groups)+1), bh.groups...), name)
+	return &bh2
 }
 
 // Handle the record.
 func (bh *batchingHandler) Handle(ctx context.Context, record slog.Record) error {
-	bh.mu.Lock()
-	defer bh.mu.Unlock()
-	bh.backlog = append(bh.backlog, record)
-	if bh.size >= 0 && len(bh.backlog) >= bh.size {
-		bh.flush(ctx)
+	b := bh.batch
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.format != nil {
+		record = bh.withAttrs(record)
+	} else {
+		record = record.Clone()
+	}
+	b.backlog = append(b.backlog, batchEntry{h: bh.h, r: record})
+	if b.flushLevel != nil && record.Level >= b.flushLevel.Level() {
+		return b.flush(ctx)
+	}
+	if b.size >= 0 && len(b.backlog) >= b.size {
+		b.flush(ctx)
 		return nil
 	}
-	if bh.interval > 0 {
-		bh.initOnce.Do(func() {
-			ticker := time.NewTicker(bh.interval)
+	if b.interval > 0 {
+		b.initOnce.Do(func() {
+			f := b.newTicker
+			if f == nil {
+				f = newTicker
+			}
+			tickC, stop := f(b.interval)
 			ctx := ctx
 			go func() {
-				defer ticker.Stop()
+				defer stop()
 				if err := ctx.Err(); err != nil {
 					ctx = context.Background()
 				}
-				for range ticker.C {
-					bh.Flush(ctx)
+				for {
+					select {
+					case <-b.done:
+						return
+					case _, ok := <-tickC:
+						if !ok {
+							return
+						}
+						b.Flush(ctx)
+					}
 				}
 			}()
 		})
@@ -143,21 +341,138 @@ func (bh *batchingHandler) Handle(ctx context.Context, record slog.Record) error
 }
 
 // Flush the records in the backlog to  the underlying Handler.
-func (bh *batchingHandler) Flush(ctx context.Context) error {
-	bh.mu.Lock()
-	err := bh.flush(ctx)
-	bh.mu.Unlock()
+func (b *batch) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	err := b.flush(ctx)
+	b.mu.Unlock()
 	return err
 }
 
+// withAttrs returns the record with the attrs of WithAttrs prepended, and its attrs in the groups of WithGroup.
+func (bh *batchingHandler) withAttrs(r slog.Record) slog.Record {
+	if len(bh.attrs) == 0 && len(bh.groups) == 0 {
+		return r.Clone()
+	}
+	r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r2.AddAttrs(bh.attrs...)
+	if r.NumAttrs() != 0 {
+		attrs := make([]slog.Attr, 0, r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool { attrs = append(attrs, a); return true })
+		r2.AddAttrs(inGroups(bh.groups, attrs)...)
+	}
+	return r2
+}
+
+// inGroups returns the attrs nested in the groups.
+func inGroups(groups []string, attrs []slog.Attr) []slog.Attr {
+	for i := len(groups) - 1; i >= 0; i-- {
+		attrs = []slog.Attr{{Key: groups[i], Value: slog.GroupValue(attrs...)}}
+	}
+	return attrs
+}
+
+// Close stops the periodic flusher, flushes the backlog, then closes the underlying Handler (if it is a Closer).
+//
+// The records handled after Close are flushed only when the backlog is full (or by Flush).
+func (bh *batchingHandler) Close() error {
+	b := bh.batch
+	b.closeOnce.Do(func() {
+		b.initOnce.Do(func() {}) // do not start the flusher after Close
+		close(b.done)
+	})
+	return errors.Join(b.Flush(context.Background()), closeHandler(bh.h))
+}
+
 // flush the records (no lock is held).
-func (bh *batchingHandler) flush(ctx context.Context) error {
+func (b *batch) flush(ctx context.Context) error {
+	if b.format != nil {
+		return b.flushFormatted()
+	}
 	var firstErr error
-	for _, rec := range bh.backlog {
-		if err := bh.h.Handle(ctx, rec); err != nil && firstErr == nil {
+	for i, e := range b.backlog {
+		if err := e.h.Handle(ctx, e.r); err != nil && firstErr == nil {
 			firstErr = err
 		}
+		b.backlog[i] = batchEntry{}
 	}
-	bh.backlog = bh.backlog[:0]
+	b.backlog = b.backlog[:0]
 	return firstErr
 }
+
+// flushFormatted writes the records formatted by format to w (no lock is held).
+func (b *batch) flushFormatted() error {
+	if len(b.backlog) == 0 {
+		return nil
+	}
+	records := make([]slog.Record, len(b.backlog))
+	for i, e := range b.backlog {
+		records[i] = e.r
+		b.backlog[i] = batchEntry{}
+	}
+	b.backlog = b.backlog[:0]
+	p, err := b.format(records)
+	if err != nil {
+		return err
+	}
+	_, err = b.w.Write(p)
+	return err
+}
+
+type flusher interface {
+	Flush(context.Context) error
+}
+
+// flushHandler flushes h if it is a flusher, or the Handler it wraps (if it has a Handler() method).
+func flushHandler(ctx context.Context, h slog.Handler) error {
+	switch x := h.(type) {
+	case nil:
+		return nil
+	case flusher:
+		return x.Flush(ctx)
+	case interface{ Handler() slog.Handler }:
+		return flushHandler(ctx, x.Handler())
+	}
+	return nil
+}
+
+// Closer is implemented by the handlers holding resources (files, connections), such as the PerLevelFileHandler.
+type Closer interface {
+	Close() error
+}
+
+// closeHandler closes h if it is a Closer, or the Handlers it wraps
+// (the children of a MultiHandler, or the Handler of a Handler() method).
+func closeHandler(h slog.Handler) error {
+	switch x := h.(type) {
+	case nil:
+		return nil
+	case Closer:
+		return x.Close()
+	case *MultiHandler:
+		var errs []error
+		for _, h := range x.ws.Load().([]slog.Handler) {
+			if err := closeHandler(h); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	case *lazyHandler:
+		return closeHandler(x.builtHandler())
+	case interface{ Handler() slog.Handler }:
+		return closeHandler(x.Handler())
+	}
+	return nil
+}
+
+type sourceToggler interface {
+	WithSource(addSource bool) slog.Handler
+}
+
+// withSource returns h with AddSource set to addSource, if h supports it;
+// h unchanged otherwise.
+func withSource(h slog.Handler, addSource bool) slog.Handler {
+	if st, ok := h.(sourceToggler); ok {
+		return st.WithSource(addSource)
+	}
+	return h
+}
@@ -0,0 +1,234 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+var _ = slog.Handler((*BackoffDedupHandler)(nil))
+
+// BackoffDedupHandler throttles the identical records with exponential backoff:
+// only the 1st, 2nd, 4th, 8th... occurrence of each key is passed,
+// with the "count" of occurrences so far and the number of "suppressed" records since the previous one.
+//
+// With Window, the 1st occurrence is passed, and at the end of each Window with suppressed records,
+// the last suppressed record is passed as a summary (by a timer, or by Flush).
+// The counters of a key are dropped after a Window without records, so the keys may have unbounded cardinality.
+//
+// The records below Level (iff set) are never suppressed - nor the AuditLevel records.
+//
+// Without Window the counters are kept for the lifetime of the handler (shared with the handlers derived from it),
+// so the keys must have a bounded cardinality.
+type BackoffDedupHandler struct {
+	handler slog.Handler
+	keyFn   func(slog.Record) string
+	counts  *backoffCounts
+	// Level is the minimum level of the deduplicated records (all if nil).
+	Level slog.Leveler
+	// Window replaces the exponential backoff with passing at most one record (and a summary) per Window (iff > 0).
+	Window time.Duration
+}
+
+type backoffCounts struct {
+	mu sync.Mutex
+	m  map[string]*backoffCount
+}
+
+type backoffCount struct {
+	// n is the number of occurrences, passed is the value of n at the last passed record
+	n, passed uint64
+	// timer ends the current window (with Window)
+	timer *time.Timer
+	// pending is the last suppressed record, to be passed as the summary at the end of the window (with Window)
+	pending *backoffPending
+}
+
+type backoffPending struct {
+	h *BackoffDedupHandler
+	r slog.Record
+}
+
+// NewBackoffDedupHandler returns a new BackoffDedupHandler, identifying the records with keyFn.
+//
+// The default keyFn (if nil) uses the level, the message and the ErrorKey attr.
+func NewBackoffDedupHandler(h slog.Handler, keyFn func(slog.Record) string) *BackoffDedupHandler {
+	if keyFn == nil {
+		keyFn = defaultDedupKey
+	}
+	return &BackoffDedupHandler{handler: h, keyFn: keyFn, counts: &backoffCounts{m: make(map[string]*backoffCount)}}
+}
+
+func defaultDedupKey(r slog.Record) string {
+	key := r.Level.String() + "\x00" + r.Message
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == ErrorKey {
+			key += "\x00" + a.Value.Resolve().String()
+			return false
+		}
+		return true
+	})
+	return key
+}
+
+// errorDedupKey identifies the records by the error's message (the ErrorKey attr),
+// falling back to defaultDedupKey for the records without error.
+func errorDedupKey(r slog.Record) string {
+	var key string
+	var found bool
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == ErrorKey {
+			key, found = a.Value.Resolve().String(), true
+			return false
+		}
+		return true
+	})
+	if !found {
+		return defaultDedupKey(r)
+	}
+	return "\x00" + key
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *BackoffDedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *BackoffDedupHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= AuditLevel || h.Level != nil && r.Level < h.Level.Level() {
+		return h.handler.Handle(ctx, r)
+	}
+	if h.Window > 0 {
+		return h.handleWindow(ctx, r)
+	}
+	key := h.keyFn(r)
+	h.counts.mu.Lock()
+	c := h.counts.m[key]
+	if c == nil {
+		c = new(backoffCount)
+		h.counts.m[key] = c
+	}
+	c.n++
+	n, suppressed := c.n, c.n-c.passed-1
+	pass := n&(n-1) == 0 // a power of 2
+	if pass {
+		c.passed = n
+	}
+	h.counts.mu.Unlock()
+	if !pass {
+		return nil
+	}
+	return h.handle(ctx, r, n, suppressed)
+}
+
+// handle passes the record, with the count and suppressed attrs if n > 1.
+func (h *BackoffDedupHandler) handle(ctx context.Context, r slog.Record, n, suppressed uint64) error {
+	if n > 1 {
+		r = r.Clone()
+		r.AddAttrs(slog.Uint64("count", n), slog.Uint64("suppressed", suppressed))
+	}
+	return h.handler.Handle(ctx, r)
+}
+
+// handleWindow passes the first record of the key and starts its window, suppressing the rest till the window's end.
+func (h *BackoffDedupHandler) handleWindow(ctx context.Context, r slog.Record) error {
+	key := h.keyFn(r)
+	h.counts.mu.Lock()
+	c := h.counts.m[key]
+	if c == nil {
+		c = new(backoffCount)
+		h.counts.m[key] = c
+	}
+	c.n++
+	if c.timer != nil { // in a window
+		c.pending = &backoffPending{h: h, r: r.Clone()}
+		h.counts.mu.Unlock()
+		return nil
+	}
+	n, suppressed := c.n, c.n-c.passed-1
+	c.passed = n
+	c.timer = time.AfterFunc(h.Window, func() { h.endWindow(key) })
+	h.counts.mu.Unlock()
+	return h.handle(ctx, r, n, suppressed)
+}
+
+// endWindow passes the pending summary of the key and starts a new window,
+// or drops the counters of the key if there were no records in the window.
+func (h *BackoffDedupHandler) endWindow(key string) {
+	h.counts.mu.Lock()
+	c := h.counts.m[key]
+	if c == nil {
+		h.counts.mu.Unlock()
+		return
+	}
+	p, n, suppressed := c.pending, c.n, c.n-c.passed-1
+	if p == nil {
+		delete(h.counts.m, key)
+		h.counts.mu.Unlock()
+		return
+	}
+	c.pending, c.passed = nil, n
+	c.timer = time.AfterFunc(h.Window, func() { h.endWindow(key) })
+	h.counts.mu.Unlock()
+	_ = p.h.handle(context.Background(), p.r, n, suppressed)
+}
+
+// Flush passes the pending summaries (with Window) and drops all the counters,
+// then flushes the underlying Handler.
+func (h *BackoffDedupHandler) Flush(ctx context.Context) error {
+	type summary struct {
+		p             *backoffPending
+		n, suppressed uint64
+	}
+	var summaries []summary
+	h.counts.mu.Lock()
+	for key, c := range h.counts.m {
+		if c.timer == nil { // without Window
+			continue
+		}
+		c.timer.Stop()
+		if c.pending != nil {
+			summaries = append(summaries, summary{p: c.pending, n: c.n, suppressed: c.n - c.passed - 1})
+		}
+		delete(h.counts.m, key)
+	}
+	h.counts.mu.Unlock()
+	var errs []error
+	for _, s := range summaries {
+		if err := s.p.h.handle(ctx, s.p.r, s.n, s.suppressed); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := flushHandler(ctx, h.handler); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *BackoffDedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.handler = h.handler.WithAttrs(attrs)
+	return &h2
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *BackoffDedupHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.handler = h.handler.WithGroup(name)
+	return &h2
+}
+
+// Handler returns the Handler wrapped by h.
+func (h *BackoffDedupHandler) Handler() slog.Handler { return h.handler }
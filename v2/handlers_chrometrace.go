@@ -0,0 +1,230 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+var _ = slog.Handler((*ChromeTraceHandler)(nil))
+
+// ChromeTraceHandler writes the records tagged as trace events
+// in the Chrome Trace Event Format (JSON Array Format),
+// viewable in chrome://tracing or https://ui.perfetto.dev .
+//
+// The expected attr schema (read regardless of the groups of WithGroup):
+//
+//	ph   string     - the phase: "B" (begin), "E" (end), "X" (complete), "i" (instant)...; required
+//	name string     - the event name; defaults to the message
+//	cat  string     - comma separated categories; optional
+//	ts   time.Time  - the timestamp; defaults to the record's time
+//	dur  time.Duration - the duration for "X" events; optional
+//	pid, tid int    - process and thread IDs; pid defaults to os.Getpid(), tid to 0
+//
+// All other attrs go into the event's "args".
+// Records without a "ph" attr are ignored.
+//
+// The closing "]" is optional in this format, Close writes it.
+type ChromeTraceHandler struct {
+	fw *framedWriter
+	// event are the trace event attrs (ph, name...) of WithAttrs, regardless of the groups
+	event     []slog.Attr
+	withAttrs []slog.Attr
+	groups    []string
+}
+
+type chromeTraceEvent struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`
+	Dur  float64        `json:"dur,omitempty"`
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// NewChromeTraceHandler returns a new ChromeTraceHandler writing to w.
+func NewChromeTraceHandler(w io.Writer) *ChromeTraceHandler {
+	return &ChromeTraceHandler{fw: &framedWriter{w: w, header: "[\n", sep: ",\n", footer: "\n]\n"}}
+}
+
+// Enabled implements slog.Handler.Enabled - all levels are enabled, filtering is done on the "ph" attr.
+func (h *ChromeTraceHandler) Enabled(ctx context.Context, level slog.Level) bool { return true }
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *ChromeTraceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	event, attrs := splitChromeTraceAttrs(attrs)
+	h2.event = append(append(make([]slog.Attr, 0, len(h.event)+len(event)), h.event...), event...)
+	if len(attrs) == 0 {
+		return &h2
+	}
+	if len(h.groups) != 0 {
+		attrs = []slog.Attr{groupAttr(h.groups, attrs)}
+	}
+	h2.withAttrs = append(append(make([]slog.Attr, 0, len(h.withAttrs)+len(attrs)), h.withAttrs...), attrs...)
+	return &h2
+}
+
+// isChromeTraceKey reports whether the key is one of the trace event attrs (ph, name, cat, ts, dur, pid, tid).
+func isChromeTraceKey(key string) bool {
+	switch key {
+	case "ph", "name", "cat", "ts", "dur", "pid", "tid":
+		return true
+	}
+	return false
+}
+
+// splitChromeTraceAttrs splits the trace event attrs from the rest.
+func splitChromeTraceAttrs(attrs []slog.Attr) (event, rest []slog.Attr) {
+	for _, a := range attrs {
+		if isChromeTraceKey(a.Key) {
+			event = append(event, a)
+		} else {
+			rest = append(rest, a)
+		}
+	}
+	return event, rest
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *ChromeTraceHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(append(make([]string, 0, len(h.groups)+1), h.groups...), name)
+	return &h2
+}
+
+// groupAttr nests the attrs into the given groups.
+func groupAttr(groups []string, attrs []slog.Attr) slog.Attr {
+	a := slog.Attr{Key: groups[len(groups)-1], Value: slog.GroupValue(attrs...)}
+	for i := len(groups) - 2; i >= 0; i-- {
+		a = slog.Attr{Key: groups[i], Value: slog.GroupValue(a)}
+	}
+	return a
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *ChromeTraceHandler) Handle(ctx context.Context, r slog.Record) error {
+	ev := chromeTraceEvent{Name: r.Message, Pid: os.Getpid(), Ts: timeToMicros(r.Time)}
+	args := make(map[string]any)
+	handle := func(a slog.Attr) bool {
+		a.Value = a.Value.Resolve()
+		switch a.Key {
+		case "ph":
+			ev.Ph = a.Value.String()
+		case "name":
+			ev.Name = a.Value.String()
+		case "cat":
+			ev.Cat = a.Value.String()
+		case "ts":
+			if t, ok := a.Value.Any().(time.Time); ok {
+				ev.Ts = timeToMicros(t)
+			}
+		case "dur":
+			if d, ok := a.Value.Any().(time.Duration); ok {
+				ev.Dur = float64(d) / float64(time.Microsecond)
+			}
+		case "pid":
+			if i, ok := a.Value.Any().(int64); ok {
+				ev.Pid = int(i)
+			}
+		case "tid":
+			if i, ok := a.Value.Any().(int64); ok {
+				ev.Tid = int(i)
+			}
+		default:
+			if a.Key != "" {
+				setArg(args, a.Key, a.Value)
+			}
+		}
+		return true
+	}
+	for _, a := range h.event {
+		handle(a)
+	}
+	for _, a := range h.withAttrs {
+		handle(a)
+	}
+	if len(h.groups) == 0 {
+		r.Attrs(handle)
+	} else {
+		// the trace event attrs are read before the groups are applied
+		attrs := make([]slog.Attr, 0, r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool {
+			if isChromeTraceKey(a.Key) {
+				handle(a)
+			} else {
+				attrs = append(attrs, a)
+			}
+			return true
+		})
+		if len(attrs) != 0 {
+			handle(groupAttr(h.groups, attrs))
+		}
+	}
+	if ev.Ph == "" {
+		return nil
+	}
+	if len(args) != 0 {
+		ev.Args = args
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return h.fw.writeElement(b)
+}
+
+// Close writes the closing "]" of the JSON array (iff any event has been written).
+func (h *ChromeTraceHandler) Close() error { return h.fw.close() }
+
+func timeToMicros(t time.Time) float64 {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return float64(t.UnixNano()) / float64(time.Microsecond)
+}
+
+// attrValueToAny converts the slog.Value to a JSON-marshalable value, groups to maps.
+func attrValueToAny(v slog.Value) any {
+	v = v.Resolve()
+	if v.Kind() != slog.KindGroup {
+		return v.Any()
+	}
+	attrs := v.Group()
+	m := make(map[string]any, len(attrs))
+	for _, a := range attrs {
+		setArg(m, a.Key, a.Value)
+	}
+	return m
+}
+
+// setArg sets m[key] to v, merging a group into the map of the same group
+// (the WithAttrs and the record attrs of the same WithGroup).
+func setArg(m map[string]any, key string, v slog.Value) {
+	v = v.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if sub, ok := m[key].(map[string]any); ok {
+			for _, a := range v.Group() {
+				setArg(sub, a.Key, a.Value)
+			}
+			return
+		}
+	}
+	m[key] = attrValueToAny(v)
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+type contextAttrsKey struct{}
+
+// NewContextWithAttrs returns a new context carrying the given attrs (appended to the ones already in ctx).
+//
+// These ambient, context-scoped attrs are added to the records by ContextAttrsHandler
+// (which is part of the handler chain of New), when logging with the *Context methods.
+//
+// This is independent of the Logger embedded with NewContext and returned by FromContext:
+// the attrs are not bound to that Logger, but to the context used for logging.
+func NewContextWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	old := AttrsFromContext(ctx)
+	return context.WithValue(ctx, contextAttrsKey{},
+		append(append(make([]slog.Attr, 0, len(old)+len(attrs)), old...), attrs...))
+}
+
+// AttrsFromContext returns the attrs set with NewContextWithAttrs.
+func AttrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(contextAttrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// MergeContextAttrs returns the union of the attrs of the contexts (see NewContextWithAttrs),
+// for logging in fan-in scenarios (a worker processing items with their own contexts):
+//
+//	ctx = zlog.NewContextWithAttrs(ctx, zlog.MergeContextAttrs(itemCtxs...)...)
+//
+// For conflicting keys the last one wins, at the place of the first one.
+func MergeContextAttrs(ctxs ...context.Context) []slog.Attr {
+	var attrs []slog.Attr
+	seen := make(map[string]int)
+	for _, ctx := range ctxs {
+		for _, a := range AttrsFromContext(ctx) {
+			if i, ok := seen[a.Key]; ok {
+				attrs[i] = a
+				continue
+			}
+			seen[a.Key] = len(attrs)
+			attrs = append(attrs, a)
+		}
+	}
+	return attrs
+}
+
+var _ = slog.Handler(ContextAttrsHandler{})
+
+// ContextAttrsHandler adds the attrs of the context (see NewContextWithAttrs) to the record.
+type ContextAttrsHandler struct {
+	handler slog.Handler
+}
+
+// NewContextAttrsHandler returns a new ContextAttrsHandler wrapping h.
+func NewContextAttrsHandler(h slog.Handler) ContextAttrsHandler {
+	if ch, ok := h.(ContextAttrsHandler); ok {
+		return ch
+	}
+	return ContextAttrsHandler{handler: h}
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h ContextAttrsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+func (h ContextAttrsHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := AttrsFromContext(ctx); len(attrs) != 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h ContextAttrsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return ContextAttrsHandler{handler: h.handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h ContextAttrsHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return ContextAttrsHandler{handler: h.handler.WithGroup(name)}
+}
+
+// WithSource returns a new ContextAttrsHandler with the source toggled on the underlying Handler (if it supports it).
+func (h ContextAttrsHandler) WithSource(addSource bool) slog.Handler {
+	return ContextAttrsHandler{handler: withSource(h.handler, addSource)}
+}
+
+// Handler returns the Handler wrapped by h.
+func (h ContextAttrsHandler) Handler() slog.Handler { return h.handler }
@@ -0,0 +1,110 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+var _ = slog.Handler((*ErrorDetailHandler)(nil))
+
+// ErrorDetailHandler adds a "<key>_detail" (for example "error_detail") attr
+// for each error attr whose %+v rendering (stack traces of wrapped errors, pkg/errors)
+// differs from its Error().
+type ErrorDetailHandler struct {
+	handler slog.Handler
+	// maxLen bounds the length of the detail (in bytes), if > 0.
+	maxLen int
+}
+
+// NewErrorDetailHandler returns a new ErrorDetailHandler wrapping h,
+// truncating the details to maxLen bytes (if maxLen > 0).
+func NewErrorDetailHandler(h slog.Handler, maxLen int) *ErrorDetailHandler {
+	return &ErrorDetailHandler{handler: h, maxLen: maxLen}
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *ErrorDetailHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *ErrorDetailHandler) Handle(ctx context.Context, r slog.Record) error {
+	var details []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		if d, ok := h.detail(a); ok {
+			details = append(details, d)
+		}
+		return true
+	})
+	if len(details) != 0 {
+		r = r.Clone()
+		r.AddAttrs(details...)
+	}
+	return h.handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *ErrorDetailHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var details []slog.Attr
+	for _, a := range attrs {
+		if d, ok := h.detail(a); ok {
+			details = append(details, d)
+		}
+	}
+	if len(details) != 0 {
+		attrs = append(append(make([]slog.Attr, 0, len(attrs)+len(details)), attrs...), details...)
+	}
+	return &ErrorDetailHandler{handler: h.handler.WithAttrs(attrs), maxLen: h.maxLen}
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *ErrorDetailHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &ErrorDetailHandler{handler: h.handler.WithGroup(name), maxLen: h.maxLen}
+}
+
+// Handler returns the Handler wrapped by h.
+func (h *ErrorDetailHandler) Handler() slog.Handler { return h.handler }
+
+func (h *ErrorDetailHandler) detail(a slog.Attr) (slog.Attr, bool) {
+	var err error
+	switch a.Value.Kind() {
+	case slog.KindAny:
+		err, _ = a.Value.Any().(error)
+	case slog.KindLogValuer: // the attr of Logger.Error and the like
+		if ev, ok := a.Value.Any().(errorValue); ok {
+			err = ev.err
+		}
+	}
+	if err == nil {
+		return zeroAttr, false
+	}
+	detail := fmt.Sprintf("%+v", err)
+	if detail == err.Error() {
+		return zeroAttr, false
+	}
+	if h.maxLen > 0 && len(detail) > h.maxLen {
+		detail = truncateString(detail, h.maxLen)
+	}
+	return slog.String(a.Key+"_detail", detail), true
+}
+
+// truncateString truncates s to at most maxLen bytes (not splitting runes), appending an ellipsis.
+func truncateString(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	for maxLen > 0 && !utf8.RuneStart(s[maxLen]) {
+		maxLen--
+	}
+	return s[:maxLen] + "…"
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+var _ = slog.Handler((*HTMLHandler)(nil))
+
+// HTMLHandler renders the records as the rows of an HTML table, with level-colored rows
+// (the same colors as the console's level labels), suitable for embedding into an email body.
+//
+// The header is written before the first row, the footer by Close.
+type HTMLHandler struct {
+	fw *framedWriter
+	// attrs are the rendered "key=value" attrs of WithAttrs
+	attrs  []string
+	prefix string
+}
+
+const (
+	htmlHeader = `<table style="border-collapse:collapse;font-family:monospace">
+<tr><th>Time</th><th>Level</th><th>Message</th><th>Attrs</th></tr>
+`
+	htmlFooter = "</table>\n"
+)
+
+// NewHTMLHandler returns a new HTMLHandler writing to w.
+//
+// All levels are enabled, wrap it in a LevelHandler for filtering.
+func NewHTMLHandler(w io.Writer) *HTMLHandler {
+	return &HTMLHandler{fw: &framedWriter{w: w, header: htmlHeader, footer: htmlFooter, empty: htmlHeader + htmlFooter}}
+}
+
+// Enabled implements slog.Handler.Enabled - all levels are enabled.
+func (h *HTMLHandler) Enabled(ctx context.Context, level slog.Level) bool { return true }
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *HTMLHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = append(make([]string, 0, len(h.attrs)+len(attrs)), h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = appendHTMLAttr(h2.attrs, h.prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *HTMLHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *HTMLHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := append(make([]string, 0, len(h.attrs)+r.NumAttrs()), h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = appendHTMLAttr(attrs, h.prefix, a)
+		return true
+	})
+	level := levelLabel(r.Level)
+	var buf strings.Builder
+	buf.WriteString(`<tr style="color:`)
+	buf.WriteString(levelColor(level).css())
+	buf.WriteString(`"><td>`)
+	buf.WriteString(html.EscapeString(r.Time.Format(TimeFormat)))
+	buf.WriteString("</td><td>")
+	buf.WriteString(level)
+	buf.WriteString("</td><td>")
+	buf.WriteString(html.EscapeString(r.Message))
+	buf.WriteString("</td><td>")
+	buf.WriteString(strings.Join(attrs, "<br>"))
+	buf.WriteString("</td></tr>\n")
+
+	return h.fw.writeElement([]byte(buf.String()))
+}
+
+// Close writes the footer (and the header, if no record has been written).
+func (h *HTMLHandler) Close() error { return h.fw.close() }
+
+// appendHTMLAttr appends the HTML-escaped "key=value" of the attr, groups flattened with "." separated keys.
+func appendHTMLAttr(dst []string, prefix string, a slog.Attr) []string {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return dst
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, g := range a.Value.Group() {
+			dst = appendHTMLAttr(dst, prefix, g)
+		}
+		return dst
+	}
+	return append(dst, html.EscapeString(prefix+a.Key+"="+a.Value.String()))
+}
+
+// css returns the CSS color name of the color.
+func (c Color) css() string {
+	switch c {
+	case Red:
+		return "red"
+	case Green:
+		return "green"
+	case Yellow:
+		return "darkgoldenrod"
+	case Blue:
+		return "blue"
+	case Magenta:
+		return "magenta"
+	case Cyan:
+		return "darkcyan"
+	case White:
+		return "gray"
+	default:
+		return "black"
+	}
+}
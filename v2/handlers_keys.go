@@ -0,0 +1,127 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+var _ = slog.Handler((*KeyNormalizeHandler)(nil))
+
+// KeyNormalizeHandler rewrites the attr keys (including the keys within groups, and the group names)
+// with a normalizer function, enforcing a naming convention at the logging boundary.
+type KeyNormalizeHandler struct {
+	handler   slog.Handler
+	normalize func(string) string
+}
+
+// NewKeyNormalizeHandler returns a new KeyNormalizeHandler wrapping h,
+// normalizing the keys with normalize (SnakeCase if nil).
+func NewKeyNormalizeHandler(h slog.Handler, normalize func(string) string) *KeyNormalizeHandler {
+	if normalize == nil {
+		normalize = SnakeCase
+	}
+	return &KeyNormalizeHandler{handler: h, normalize: normalize}
+}
+
+// SnakeCase converts the camelCase (or PascalCase) key to snake_case:
+// "userID" to "user_id", "HTTPStatus" to "http_status".
+func SnakeCase(s string) string {
+	var hasUpper bool
+	for i := 0; i < len(s); i++ {
+		if b := s[i]; b >= utf8.RuneSelf || ('A' <= b && b <= 'Z') {
+			hasUpper = true
+			break
+		}
+	}
+	if !hasUpper {
+		return s
+	}
+	rs := []rune(s)
+	var buf strings.Builder
+	buf.Grow(len(s) + 4)
+	for i, r := range rs {
+		if !unicode.IsUpper(r) {
+			buf.WriteRune(r)
+			continue
+		}
+		if i > 0 && rs[i-1] != '_' &&
+			(unicode.IsLower(rs[i-1]) || unicode.IsDigit(rs[i-1]) ||
+				(i+1 < len(rs) && unicode.IsLower(rs[i+1]))) {
+			buf.WriteByte('_')
+		}
+		buf.WriteRune(unicode.ToLower(r))
+	}
+	return buf.String()
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *KeyNormalizeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *KeyNormalizeHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.NumAttrs() == 0 {
+		return h.handler.Handle(ctx, r)
+	}
+	r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		r2.AddAttrs(h.normalizeAttr(a))
+		return true
+	})
+	return h.handler.Handle(ctx, r2)
+}
+
+// normalizeAttr returns the attr with normalized key, recursing into the groups.
+func (h *KeyNormalizeHandler) normalizeAttr(a slog.Attr) slog.Attr {
+	a.Key = h.normalize(a.Key)
+	if a.Value.Kind() == slog.KindLogValuer {
+		a.Value = a.Value.Resolve()
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return a
+	}
+	group := a.Value.Group()
+	attrs := make([]slog.Attr, len(group))
+	for i, g := range group {
+		attrs[i] = h.normalizeAttr(g)
+	}
+	a.Value = slog.GroupValue(attrs...)
+	return a
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *KeyNormalizeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	normalized := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		normalized[i] = h.normalizeAttr(a)
+	}
+	return &KeyNormalizeHandler{handler: h.handler.WithAttrs(normalized), normalize: h.normalize}
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *KeyNormalizeHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &KeyNormalizeHandler{handler: h.handler.WithGroup(h.normalize(name)), normalize: h.normalize}
+}
+
+// Handler returns the Handler wrapped by h.
+func (h *KeyNormalizeHandler) Handler() slog.Handler { return h.handler }
+
+// WithSource returns a new KeyNormalizeHandler with the source toggled on the underlying Handler (if it supports it).
+func (h *KeyNormalizeHandler) WithSource(addSource bool) slog.Handler {
+	return &KeyNormalizeHandler{handler: withSource(h.handler, addSource), normalize: h.normalize}
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+// NewLazyLogger returns a Logger whose handler is built by newHandler on its first use
+// (the first Enabled or Handle call, of it or any Logger derived from it), exactly once,
+// deferring the expensive setup (file opens, network dials) until something is actually logged.
+//
+// Handlers derived (WithAttrs, WithGroup) before the first use are lazy, too.
+// SetLevel, V, Flush and Close don't build the handler: SetLevel wraps it in a LevelHandler,
+// V computes its level on its own first use, Flush and Close are no-ops before the first use.
+func NewLazyLogger(newHandler func() slog.Handler) Logger {
+	return NewLogger(&lazyHandler{state: &lazyState{newHandler: newHandler}})
+}
+
+type lazyState struct {
+	newHandler func() slog.Handler
+	handler    slog.Handler
+	once       sync.Once
+	built      atomic.Bool
+}
+
+var _ = slog.Handler((*lazyHandler)(nil))
+
+// lazyHandler builds its handler on the first use.
+type lazyHandler struct {
+	state *lazyState
+}
+
+// Handler returns the underlying Handler, building it if needed.
+func (h *lazyHandler) Handler() slog.Handler {
+	s := h.state
+	s.once.Do(func() {
+		if s.handler = s.newHandler(); s.handler == nil {
+			s.handler = discard().Handler()
+		}
+		s.built.Store(true)
+	})
+	return s.handler
+}
+
+// builtHandler returns the underlying Handler iff it has been built, nil otherwise:
+// for the handler tree walkers (levelHandlers, closeHandler), which must not build it.
+func (h *lazyHandler) builtHandler() slog.Handler {
+	if !h.state.built.Load() {
+		return nil
+	}
+	return h.state.handler
+}
+
+func (h *lazyHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.Handler().Enabled(ctx, level)
+}
+func (h *lazyHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.Handler().Handle(ctx, r)
+}
+func (h *lazyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.state.built.Load() {
+		return h.Handler().WithAttrs(attrs)
+	}
+	return &lazyHandler{state: &lazyState{newHandler: func() slog.Handler { return h.Handler().WithAttrs(attrs) }}}
+}
+func (h *lazyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	if h.state.built.Load() {
+		return h.Handler().WithGroup(name)
+	}
+	return &lazyHandler{state: &lazyState{newHandler: func() slog.Handler { return h.Handler().WithGroup(name) }}}
+}
+
+// Flush the underlying handler - iff it has been built.
+func (h *lazyHandler) Flush(ctx context.Context) error {
+	if !h.state.built.Load() {
+		return nil
+	}
+	return flushHandler(ctx, h.Handler())
+}
+
+// lazyLevelVar is a LevelVar whose initial level is computed by init on its first use (Level or Set),
+// so Logger.V does not build a lazy handler just to learn its level.
+type lazyLevelVar struct {
+	init func() slog.Level
+	lv   slog.LevelVar
+	once sync.Once
+}
+
+func (v *lazyLevelVar) Level() slog.Level {
+	v.once.Do(func() { v.lv.Set(v.init()) })
+	return v.lv.Level()
+}
+func (v *lazyLevelVar) Set(level slog.Level) {
+	v.once.Do(func() {})
+	v.lv.Set(level)
+}
@@ -17,6 +17,9 @@ var _ = slog.Handler((*LevelHandler)(nil))
 type LevelHandler struct {
 	level   slog.Leveler
 	handler slog.Handler
+	// own is set on the LevelHandler of Logger.V: its level is its own,
+	// so levelHandlers does not descend into (and SetLevel does not modify) the parent's handlers.
+	own bool
 }
 
 // NewLevelHandler returns a LevelHandler with the given level.
@@ -26,13 +29,50 @@ func NewLevelHandler(level slog.Leveler, h slog.Handler) *LevelHandler {
 	if lh, ok := h.(*LevelHandler); ok {
 		h = lh.Handler()
 	}
-	return &LevelHandler{level, h}
+	return &LevelHandler{level: level, handler: h}
 }
 
 // Enabled implements Handler.Enabled by reporting whether
-// level is at least as large as h's level.
+// level is at least as large as h's level - or the context's level (see NewContextLevel).
+//
+// AuditLevel is always enabled.
 func (h *LevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return level >= h.level.Level()
+	if level >= AuditLevel {
+		return true
+	}
+	minLevel := h.level.Level()
+	if ctxLevel, ok := LevelFromContext(ctx); ok && (ContextLevelCanRaise || ctxLevel.Level() < minLevel) {
+		minLevel = ctxLevel.Level()
+	}
+	return level >= minLevel
+}
+
+type contextLevelKey struct{}
+
+// ContextLevelCanRaise allows the context's level (see NewContextLevel) to raise the threshold of the LevelHandlers, too.
+//
+// By default (false) the context's level can only lower the threshold (enable more records), for safety.
+var ContextLevelCanRaise bool
+
+// NewContextLevel returns a new context carrying the level,
+// which overrides the level of the LevelHandlers (part of the handler chain of New)
+// when logging with this context (by the *Context methods) - for debugging a single request, for example:
+//
+//	ctx = zlog.NewContextLevel(ctx, zlog.TraceLevel)
+//	logger.DebugContext(ctx, "logged even if the logger is at Info")
+//
+// See ContextLevelCanRaise for the precedence.
+func NewContextLevel(ctx context.Context, level slog.Leveler) context.Context {
+	return context.WithValue(ctx, contextLevelKey{}, level)
+}
+
+// LevelFromContext returns the level set by NewContextLevel.
+func LevelFromContext(ctx context.Context) (slog.Leveler, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	level, ok := ctx.Value(contextLevelKey{}).(slog.Leveler)
+	return level, ok && level != nil
 }
 
 // SetLevel on the LevelHandler.
@@ -56,13 +96,55 @@ func (h *LevelHandler) Handle(ctx context.Context, r slog.Record) error {
 
 // WithAttrs implements Handler.WithAttrs.
 func (h *LevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return NewLevelHandler(h.level, h.handler.WithAttrs(attrs))
+	return h.with(h.handler.WithAttrs(attrs))
 }
 
 // WithGroup implements Handler.WithGroup.
 func (h *LevelHandler) WithGroup(name string) slog.Handler {
-	return NewLevelHandler(h.level, h.handler.WithGroup(name))
+	if name == "" {
+		return h
+	}
+	return h.with(h.handler.WithGroup(name))
+}
+
+// WithSource returns a new LevelHandler with the source toggled on the underlying Handler (if it supports it).
+func (h *LevelHandler) WithSource(addSource bool) slog.Handler {
+	return h.with(withSource(h.handler, addSource))
+}
+
+// with returns a new LevelHandler with the same level wrapping hndl.
+func (h *LevelHandler) with(hndl slog.Handler) *LevelHandler {
+	// Optimization: avoid chains of LevelHandlers.
+	if lh, ok := hndl.(*LevelHandler); ok {
+		hndl = lh.Handler()
+	}
+	return &LevelHandler{level: h.level, handler: hndl, own: h.own}
 }
 
 // Handler returns the Handler wrapped by h.
 func (h *LevelHandler) Handler() slog.Handler { return h.handler }
+
+// levelHandlers returns the LevelHandlers of the handler tree of h (outermost first),
+// descending into the MultiHandlers' children and the wrapped handlers (with a Handler() method),
+// but not into the lazy handlers not built yet.
+func levelHandlers(dst []*LevelHandler, h slog.Handler) []*LevelHandler {
+	switch x := h.(type) {
+	case nil:
+		return dst
+	case *LevelHandler:
+		if x.own {
+			return append(dst, x)
+		}
+		return levelHandlers(append(dst, x), x.handler)
+	case *MultiHandler:
+		for _, h := range x.ws.Load().([]slog.Handler) {
+			dst = levelHandlers(dst, h)
+		}
+		return dst
+	case *lazyHandler:
+		return levelHandlers(dst, x.builtHandler())
+	case interface{ Handler() slog.Handler }:
+		return levelHandlers(dst, x.Handler())
+	}
+	return dst
+}
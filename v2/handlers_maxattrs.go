@@ -0,0 +1,77 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"sync"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+var _ = slog.Handler((*MaxAttrsHandler)(nil))
+
+// MaxAttrsHandler keeps only the first max attrs of each record,
+// replacing the rest with a single attrs_truncated=N attr.
+//
+// Only the top-level attrs of the record are counted.
+type MaxAttrsHandler struct {
+	handler  slog.Handler
+	warnOnce *sync.Once
+	max      int
+}
+
+// NewMaxAttrsHandler returns a new MaxAttrsHandler, wrapping h.
+func NewMaxAttrsHandler(h slog.Handler, max int) *MaxAttrsHandler {
+	return &MaxAttrsHandler{handler: h, max: max, warnOnce: new(sync.Once)}
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *MaxAttrsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+//
+// The first truncation emits a one-time warning.
+func (h *MaxAttrsHandler) Handle(ctx context.Context, r slog.Record) error {
+	n := r.NumAttrs()
+	if h.max < 0 || n <= h.max {
+		return h.handler.Handle(ctx, r)
+	}
+	h.warnOnce.Do(func() {
+		w := slog.NewRecord(r.Time, slog.LevelWarn, "too many attrs, truncating", r.PC)
+		w.AddAttrs(slog.Int("max", h.max), slog.Int("got", n))
+		_ = h.handler.Handle(ctx, w)
+	})
+	r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	i := 0
+	r.Attrs(func(a slog.Attr) bool {
+		if i >= h.max {
+			return false
+		}
+		r2.AddAttrs(a)
+		i++
+		return true
+	})
+	r2.AddAttrs(slog.Int("attrs_truncated", n-h.max))
+	return h.handler.Handle(ctx, r2)
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *MaxAttrsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &MaxAttrsHandler{handler: h.handler.WithAttrs(attrs), max: h.max, warnOnce: h.warnOnce}
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *MaxAttrsHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &MaxAttrsHandler{handler: h.handler.WithGroup(name), max: h.max, warnOnce: h.warnOnce}
+}
+
+// Handler returns the Handler wrapped by h.
+func (h *MaxAttrsHandler) Handler() slog.Handler { return h.handler }
@@ -0,0 +1,67 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+// MonoKey is the key of the monotonic timestamp added by MonotonicHandler.
+var MonoKey = "mono_ns"
+
+// monoStart is the base of the monotonic timestamps.
+var monoStart = time.Now()
+
+var _ = slog.Handler((*MonotonicHandler)(nil))
+
+// MonotonicHandler adds a MonoKey attr to each record: the nanoseconds elapsed since the process start,
+// measured by the monotonic clock, so the differences between the records are reliable
+// even if the wall clock jumps (NTP adjustment, for example).
+type MonotonicHandler struct {
+	handler slog.Handler
+}
+
+// NewMonotonicHandler returns a new MonotonicHandler wrapping h.
+func NewMonotonicHandler(h slog.Handler) *MonotonicHandler { return &MonotonicHandler{handler: h} }
+
+// Enabled implements slog.Handler.Enabled.
+func (h *MonotonicHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *MonotonicHandler) Handle(ctx context.Context, r slog.Record) error {
+	mono := time.Since(monoStart)
+	r = r.Clone()
+	r.AddAttrs(slog.Int64(MonoKey, int64(mono)))
+	return h.handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *MonotonicHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return &MonotonicHandler{handler: h.handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *MonotonicHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &MonotonicHandler{handler: h.handler.WithGroup(name)}
+}
+
+// Handler returns the underlying Handler.
+func (h *MonotonicHandler) Handler() slog.Handler { return h.handler }
+
+// WithSource returns a new MonotonicHandler with the source toggled on the underlying Handler (if it supports it).
+func (h *MonotonicHandler) WithSource(addSource bool) slog.Handler {
+	return &MonotonicHandler{handler: withSource(h.handler, addSource)}
+}
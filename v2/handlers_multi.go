@@ -6,6 +6,7 @@ package zlog
 
 import (
 	"context"
+	"fmt"
 	"sync/atomic"
 
 	"github.com/UNO-SOFT/zlog/v2/slog"
@@ -16,7 +17,11 @@ var _ = slog.Handler((*MultiHandler)(nil))
 // MultiHandler writes to all the specified handlers.
 //
 // goroutine-safe.
-type MultiHandler struct{ ws atomic.Value }
+type MultiHandler struct {
+	ws atomic.Value
+	// tolerant recovers the panics of the underlying handlers.
+	tolerant bool
+}
 
 // NewMultiHandler returns a new slog.Handler that writes to all the specified Handlers.
 func NewMultiHandler(hs ...slog.Handler) *MultiHandler {
@@ -25,6 +30,16 @@ func NewMultiHandler(hs ...slog.Handler) *MultiHandler {
 	return &lw
 }
 
+// NewTolerantMultiHandler returns a new MultiHandler that recovers the panic of any of the Handlers,
+// so a failing sink does not prevent the others from receiving the record.
+//
+// The panic is returned as an error from Handle.
+func NewTolerantMultiHandler(hs ...slog.Handler) *MultiHandler {
+	lw := NewMultiHandler(hs...)
+	lw.tolerant = true
+	return lw
+}
+
 // Add an additional writer to the targets.
 func (lw *MultiHandler) Add(w slog.Handler) { lw.ws.Store(append(lw.ws.Load().([]slog.Handler), w)) }
 
@@ -38,29 +53,63 @@ func (lw *MultiHandler) Handle(ctx context.Context, r slog.Record) error {
 		if !h.Enabled(ctx, r.Level) {
 			continue
 		}
-		if err := h.Handle(ctx, r); err != nil && firstErr == nil {
+		var err error
+		if lw.tolerant {
+			err = handleRecover(ctx, h, r)
+		} else {
+			err = h.Handle(ctx, r)
+		}
+		if err != nil && firstErr == nil {
 			firstErr = err
 		}
 	}
 	return firstErr
 }
 
+// handleRecover calls h.Handle, returning the panic as an error.
+func handleRecover(ctx context.Context, h slog.Handler, r slog.Record) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler %T panicked: %v", h, p)
+		}
+	}()
+	return h.Handle(ctx, r.Clone())
+}
+
 // WithAttrs returns a new slog.Handler with the given attrs set on all underlying handlers.
 func (lw *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	hs := append([]slog.Handler(nil), lw.ws.Load().([]slog.Handler)...)
 	for i, h := range hs {
 		hs[i] = h.WithAttrs(attrs)
 	}
-	return NewMultiHandler(hs...)
+	lw2 := NewMultiHandler(hs...)
+	lw2.tolerant = lw.tolerant
+	return lw2
 }
 
 // WithGroup returns a new slog.Handler with the given group set on all underlying handlers.
 func (lw *MultiHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return lw
+	}
 	hs := append([]slog.Handler(nil), lw.ws.Load().([]slog.Handler)...)
 	for i, h := range hs {
 		hs[i] = h.WithGroup(name)
 	}
-	return NewMultiHandler(hs...)
+	lw2 := NewMultiHandler(hs...)
+	lw2.tolerant = lw.tolerant
+	return lw2
+}
+
+// WithSource returns a new slog.Handler with the source toggled on all underlying handlers (that support it).
+func (lw *MultiHandler) WithSource(addSource bool) slog.Handler {
+	hs := append([]slog.Handler(nil), lw.ws.Load().([]slog.Handler)...)
+	for i, h := range hs {
+		hs[i] = withSource(h, addSource)
+	}
+	lw2 := NewMultiHandler(hs...)
+	lw2.tolerant = lw.tolerant
+	return lw2
 }
 
 // Enabled reports whether any of the underlying handlers is enabled for the given level.
@@ -72,3 +121,14 @@ func (lw *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	}
 	return false
 }
+
+// Flush all the underlying handlers which support flushing.
+func (lw *MultiHandler) Flush(ctx context.Context) error {
+	var firstErr error
+	for _, h := range lw.ws.Load().([]slog.Handler) {
+		if err := flushHandler(ctx, h); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"strings"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+// MultilineDetailKey is the key of the attr holding the tail of a multi-line message.
+const MultilineDetailKey = "detail"
+
+// splitMultilineMessage keeps the first line of a multi-line message as the message,
+// and moves the rest into a "detail" attr.
+func splitMultilineMessage(r slog.Record) slog.Record {
+	first, rest, found := strings.Cut(r.Message, "\n")
+	if !found {
+		return r
+	}
+	r = r.Clone()
+	r.Message = strings.TrimSuffix(first, "\r")
+	r.AddAttrs(slog.String(MultilineDetailKey, rest))
+	return r
+}
+
+var _ = slog.Handler(MultilineMessageHandler{})
+
+// MultilineMessageHandler keeps the first line of a multi-line message (stack traces, SQL) as the message,
+// and moves the rest into a "detail" attr.
+type MultilineMessageHandler struct {
+	handler slog.Handler
+}
+
+// NewMultilineMessageHandler returns a new MultilineMessageHandler wrapping h.
+func NewMultilineMessageHandler(h slog.Handler) MultilineMessageHandler {
+	return MultilineMessageHandler{handler: h}
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h MultilineMessageHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+func (h MultilineMessageHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler.Handle(ctx, splitMultilineMessage(r))
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h MultilineMessageHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return MultilineMessageHandler{handler: h.handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h MultilineMessageHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return MultilineMessageHandler{handler: h.handler.WithGroup(name)}
+}
+
+// Handler returns the Handler wrapped by h.
+func (h MultilineMessageHandler) Handler() slog.Handler { return h.handler }
@@ -0,0 +1,201 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+// PerLevelFileOptions are the options of NewPerLevelFileHandler.
+type PerLevelFileOptions struct {
+	// Level is the minimum level (InfoLevel if nil).
+	Level slog.Leveler
+	// OpenFile opens the file of the given path for appending - for example a rotating writer.
+	// By default it is os.OpenFile with os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644.
+	OpenFile func(path string) (io.WriteCloser, error)
+	// NewHandler returns the Handler writing to the file
+	// (DefaultHandlerOptions.NewJSONHandler, with Level, by default).
+	NewHandler func(io.Writer) slog.Handler
+}
+
+var _ = slog.Handler((*PerLevelFileHandler)(nil))
+
+// PerLevelFileHandler writes the records of each level into a separate file in a directory:
+// debug.log, info.log, warn.log, error.log and audit.log (by the console's level buckets).
+//
+// The files are created lazily, on the first record of their level.
+type PerLevelFileHandler struct {
+	files *perLevelFiles
+	// ops are the WithAttrs/WithGroup calls, replayed on the lazily created handlers
+	ops      []func(slog.Handler) slog.Handler
+	mu       sync.Mutex
+	handlers map[string]slog.Handler
+}
+
+type perLevelFiles struct {
+	opts     PerLevelFileOptions
+	dir      string
+	mu       sync.Mutex
+	files    map[string]io.WriteCloser
+	handlers map[string]slog.Handler
+	// closed is set by Close: the derived handlers' cached file handlers must not be used after it
+	closed bool
+}
+
+// NewPerLevelFileHandler returns a new PerLevelFileHandler writing into dir.
+func NewPerLevelFileHandler(dir string, opts *PerLevelFileOptions) *PerLevelFileHandler {
+	var o PerLevelFileOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Level == nil {
+		o.Level = InfoLevel
+	}
+	if o.OpenFile == nil {
+		o.OpenFile = func(path string) (io.WriteCloser, error) {
+			return os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		}
+	}
+	if o.NewHandler == nil {
+		hOpts := DefaultHandlerOptions
+		hOpts.Level = o.Level
+		o.NewHandler = hOpts.NewJSONHandler
+	}
+	return &PerLevelFileHandler{files: &perLevelFiles{
+		opts: o, dir: dir,
+		files: make(map[string]io.WriteCloser), handlers: make(map[string]slog.Handler),
+	}}
+}
+
+// Enabled implements slog.Handler.Enabled.
+//
+// AuditLevel is always enabled.
+func (h *PerLevelFileHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= AuditLevel || level >= h.files.opts.Level.Level()
+}
+
+// Handle implements slog.Handler.Handle, routing the record to the file of its level.
+//
+// It returns os.ErrClosed after Close.
+func (h *PerLevelFileHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.files.isClosed() {
+		return os.ErrClosed
+	}
+	hndl, err := h.handler(levelFileName(r.Level))
+	if err != nil {
+		return err
+	}
+	return hndl.Handle(ctx, r)
+}
+
+// handler returns the handler of the file (with the ops applied), opening the file if needed.
+func (h *PerLevelFileHandler) handler(name string) (slog.Handler, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hndl := h.handlers[name]; hndl != nil {
+		return hndl, nil
+	}
+	hndl, err := h.files.handler(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range h.ops {
+		hndl = op(hndl)
+	}
+	if h.handlers == nil {
+		h.handlers = make(map[string]slog.Handler)
+	}
+	h.handlers[name] = hndl
+	return hndl, nil
+}
+
+func (fs *perLevelFiles) isClosed() bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.closed
+}
+
+func (fs *perLevelFiles) handler(name string) (slog.Handler, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.closed {
+		return nil, os.ErrClosed
+	}
+	if hndl := fs.handlers[name]; hndl != nil {
+		return hndl, nil
+	}
+	fh, err := fs.opts.OpenFile(filepath.Join(fs.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	fs.files[name] = fh
+	hndl := fs.opts.NewHandler(fh)
+	fs.handlers[name] = hndl
+	return hndl, nil
+}
+
+// levelFileName returns the file name of the level's bucket.
+func levelFileName(level slog.Level) string {
+	switch levelLabel(level) {
+	case "DBG":
+		return "debug.log"
+	case "INF":
+		return "info.log"
+	case "WRN":
+		return "warn.log"
+	case "ERR":
+		return "error.log"
+	default:
+		return "audit.log"
+	}
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *PerLevelFileHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(func(hndl slog.Handler) slog.Handler { return hndl.WithAttrs(attrs) })
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *PerLevelFileHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(func(hndl slog.Handler) slog.Handler { return hndl.WithGroup(name) })
+}
+
+func (h *PerLevelFileHandler) with(op func(slog.Handler) slog.Handler) *PerLevelFileHandler {
+	return &PerLevelFileHandler{
+		files: h.files,
+		ops:   append(append(make([]func(slog.Handler) slog.Handler, 0, len(h.ops)+1), h.ops...), op),
+	}
+}
+
+// Close all the opened files (of h and the handlers derived from it).
+// The handlers must not be used after Close: their Handle returns os.ErrClosed.
+func (h *PerLevelFileHandler) Close() error {
+	fs := h.files
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.closed = true
+	var errs []error
+	for name, fh := range fs.files {
+		if err := fh.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(fs.files, name)
+		delete(fs.handlers, name)
+	}
+	return errors.Join(errs...)
+}
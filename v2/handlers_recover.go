@@ -0,0 +1,79 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+var _ = slog.Handler(RecoverHandler{})
+
+// RecoverHandler wraps a Handler and recovers the panics of Handle
+// (for example a panicking LogValuer or Stringer attr),
+// emitting a minimal fallback record instead.
+//
+// This is opt-in, as it may hide bugs during development.
+type RecoverHandler struct {
+	slog.Handler
+}
+
+// NewRecoverHandler returns a RecoverHandler wrapping h.
+func NewRecoverHandler(h slog.Handler) RecoverHandler {
+	if rh, ok := h.(RecoverHandler); ok {
+		return rh
+	}
+	return RecoverHandler{Handler: h}
+}
+
+// Handle implements slog.Handler.Handle.
+//
+// If the underlying Handler panics, a "log render panic" record is handled instead,
+// with the original message and the panic as attrs.
+func (h RecoverHandler) Handle(ctx context.Context, r slog.Record) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = h.handleFallback(ctx, r, p)
+		}
+	}()
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h RecoverHandler) handleFallback(ctx context.Context, r slog.Record, p any) (err error) {
+	defer func() {
+		if p2 := recover(); p2 != nil {
+			err = fmt.Errorf("log render panic: %v (fallback: %v)", p, p2)
+		}
+	}()
+	fr := slog.NewRecord(r.Time, r.Level, "log render panic", r.PC)
+	fr.AddAttrs(
+		slog.String("message", r.Message),
+		slog.String("panic", fmt.Sprintf("%v", p)),
+	)
+	return h.Handler.Handle(ctx, fr)
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h RecoverHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return RecoverHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h RecoverHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return RecoverHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// Flush the underlying Handler, if it supports flushing.
+func (h RecoverHandler) Flush(ctx context.Context) error { return flushHandler(ctx, h.Handler) }
+
+// WithSource returns a new RecoverHandler with the source toggled on the underlying Handler (if it supports it).
+func (h RecoverHandler) WithSource(addSource bool) slog.Handler {
+	return RecoverHandler{Handler: withSource(h.Handler, addSource)}
+}
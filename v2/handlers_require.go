@@ -0,0 +1,101 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+var _ = slog.Handler((*RequireAttrsHandler)(nil))
+
+// RequireAttrsHandler checks that each record has all the required (top-level) attrs,
+// for example actor, action and target for audit logs.
+//
+// In lenient mode (the default) a record with missing attrs is passed on with
+// an additional "missing_attrs" attr listing them,
+// in Strict mode such a record is not passed on, but an error is returned.
+type RequireAttrsHandler struct {
+	handler slog.Handler
+	keys    []string
+	// present holds the required keys set by WithAttrs.
+	present map[string]struct{}
+	// grouped is true after a (non-empty) WithGroup: the record's attrs are not top-level.
+	grouped bool
+	// Strict returns an error instead of marking the record.
+	Strict bool
+}
+
+// NewRequireAttrsHandler returns a new lenient RequireAttrsHandler.
+func NewRequireAttrsHandler(h slog.Handler, keys ...string) *RequireAttrsHandler {
+	return &RequireAttrsHandler{handler: h, keys: keys}
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *RequireAttrsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *RequireAttrsHandler) Handle(ctx context.Context, r slog.Record) error {
+	var missing []string
+	for _, k := range h.keys {
+		if _, ok := h.present[k]; ok {
+			continue
+		}
+		found := false
+		if !h.grouped {
+			r.Attrs(func(a slog.Attr) bool {
+				found = a.Key == k
+				return !found
+			})
+		}
+		if !found {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) == 0 {
+		return h.handler.Handle(ctx, r)
+	}
+	if h.Strict {
+		return fmt.Errorf("record %q misses the required attrs %q", r.Message, missing)
+	}
+	r = r.Clone()
+	r.AddAttrs(slog.String("missing_attrs", strings.Join(missing, ",")))
+	return h.handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *RequireAttrsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.handler = h.handler.WithAttrs(attrs)
+	if !h.grouped {
+		h2.present = make(map[string]struct{}, len(h.present)+len(attrs))
+		for k := range h.present {
+			h2.present[k] = struct{}{}
+		}
+		for _, a := range attrs {
+			h2.present[a.Key] = struct{}{}
+		}
+	}
+	return &h2
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *RequireAttrsHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.handler = h.handler.WithGroup(name)
+	h2.grouped = true
+	return &h2
+}
+
+// Handler returns the Handler wrapped by h.
+func (h *RequireAttrsHandler) Handler() slog.Handler { return h.handler }
@@ -0,0 +1,98 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+var _ = slog.Handler((*ProbabilisticSamplingHandler)(nil))
+
+// ProbabilisticSamplingHandler passes the records below a level only with the given probability,
+// and all the records at or above that level.
+type ProbabilisticSamplingHandler struct {
+	handler     slog.Handler
+	sampleBelow slog.Level
+	rate        float64
+}
+
+// NewProbabilisticSamplingHandler returns a new ProbabilisticSamplingHandler,
+// which passes the records below sampleBelow with probability rate (0.05 means 5%).
+func NewProbabilisticSamplingHandler(h slog.Handler, sampleBelow slog.Level, rate float64) *ProbabilisticSamplingHandler {
+	return &ProbabilisticSamplingHandler{handler: h, sampleBelow: sampleBelow, rate: rate}
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *ProbabilisticSamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if level < h.sampleBelow && h.rate <= 0 {
+		return false
+	}
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *ProbabilisticSamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	// the top-level functions of math/rand are goroutine-safe and cheap (when not seeded).
+	if r.Level < h.sampleBelow && (h.rate <= 0 || (h.rate < 1 && rand.Float64() >= h.rate)) {
+		return nil
+	}
+	return h.handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *ProbabilisticSamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ProbabilisticSamplingHandler{handler: h.handler.WithAttrs(attrs), sampleBelow: h.sampleBelow, rate: h.rate}
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *ProbabilisticSamplingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &ProbabilisticSamplingHandler{handler: h.handler.WithGroup(name), sampleBelow: h.sampleBelow, rate: h.rate}
+}
+
+// Handler returns the Handler wrapped by h.
+func (h *ProbabilisticSamplingHandler) Handler() slog.Handler { return h.handler }
+
+// countSamplingHandler passes every nth record, counting on a shared counter.
+type countSamplingHandler struct {
+	handler slog.Handler
+	counter *atomic.Uint64
+	n       uint64
+}
+
+// callSiteCounters are the counters of Logger.Sample, by call site.
+//
+// They are never dropped: the map grows with the number of distinct call sites of Sample
+// (bounded by the code, one small counter each), shared by all the Loggers.
+var callSiteCounters sync.Map
+
+func (h countSamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+func (h countSamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if (h.counter.Add(1)-1)%h.n != 0 {
+		return nil
+	}
+	return h.handler.Handle(ctx, r)
+}
+func (h countSamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h.handler = h.handler.WithAttrs(attrs)
+	return h
+}
+func (h countSamplingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h.handler = h.handler.WithGroup(name)
+	return h
+}
+func (h countSamplingHandler) Handler() slog.Handler { return h.handler }
@@ -0,0 +1,151 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+var _ = slog.Handler((*SchemaHandler)(nil))
+
+// SchemaHandler drops the attrs whose key is not in the allowed set (a schema lock),
+// calling onViolation with each dropped key.
+//
+// By default only the top-level keys are checked: a group (of an attr, or of WithGroup)
+// is allowed or dropped as a whole, by its name.
+// With Recurse, the keys within the groups are checked as "." separated paths ("group.key"),
+// and the groups themselves are not checked (the ones emptied are dropped).
+//
+// Pairs with the RequireAttrsHandler for full schema control.
+type SchemaHandler struct {
+	handler     slog.Handler
+	allowed     map[string]bool
+	onViolation func(key string)
+	// groups are the names of WithGroup
+	groups []string
+	// Recurse checks the keys within the groups, too.
+	Recurse bool
+}
+
+// NewSchemaHandler returns a new SchemaHandler wrapping h, allowing only the keys in allowed.
+// onViolation may be nil.
+func NewSchemaHandler(h slog.Handler, allowed map[string]bool, onViolation func(key string)) *SchemaHandler {
+	return &SchemaHandler{handler: h, allowed: allowed, onViolation: onViolation}
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *SchemaHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *SchemaHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.NumAttrs() == 0 {
+		return h.handler.Handle(ctx, r)
+	}
+	r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r2.AddAttrs(h.filter(r)...)
+	return h.handler.Handle(ctx, r2)
+}
+
+func (h *SchemaHandler) filter(r slog.Record) []slog.Attr {
+	if !h.Recurse && len(h.groups) != 0 {
+		if h.check(h.groups[0]) {
+			attrs := make([]slog.Attr, 0, r.NumAttrs())
+			r.Attrs(func(a slog.Attr) bool { attrs = append(attrs, a); return true })
+			return attrs
+		}
+		return nil
+	}
+	prefix := h.prefix()
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		if a, ok := h.filterAttr(prefix, a); ok {
+			attrs = append(attrs, a)
+		}
+		return true
+	})
+	return attrs
+}
+
+// prefix returns the "." separated path of the groups.
+func (h *SchemaHandler) prefix() string {
+	var prefix string
+	for _, g := range h.groups {
+		prefix += g + "."
+	}
+	return prefix
+}
+
+// filterAttr returns the allowed part of the attr, and whether anything is left of it.
+func (h *SchemaHandler) filterAttr(prefix string, a slog.Attr) (slog.Attr, bool) {
+	if !h.Recurse {
+		if a.Key == "" && a.Value.Kind() == slog.KindGroup { // inlined group
+			var attrs []slog.Attr
+			for _, g := range a.Value.Group() {
+				if g, ok := h.filterAttr(prefix, g); ok {
+					attrs = append(attrs, g)
+				}
+			}
+			return slog.Attr{Value: slog.GroupValue(attrs...)}, len(attrs) != 0
+		}
+		return a, h.check(a.Key)
+	}
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		return a, h.check(prefix + a.Key)
+	}
+	if a.Key != "" {
+		prefix += a.Key + "."
+	}
+	var attrs []slog.Attr
+	for _, g := range a.Value.Group() {
+		if g, ok := h.filterAttr(prefix, g); ok {
+			attrs = append(attrs, g)
+		}
+	}
+	a.Value = slog.GroupValue(attrs...)
+	return a, len(attrs) != 0
+}
+
+// check reports whether the key is allowed, calling onViolation if not.
+func (h *SchemaHandler) check(key string) bool {
+	if h.allowed[key] {
+		return true
+	}
+	if h.onViolation != nil {
+		h.onViolation(key)
+	}
+	return false
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *SchemaHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	r := slog.NewRecord(time.Time{}, 0, "", 0)
+	r.AddAttrs(attrs...)
+	h2 := *h
+	h2.handler = h.handler.WithAttrs(h.filter(r))
+	return &h2
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *SchemaHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.handler = h.handler.WithGroup(name)
+	h2.groups = append(append(make([]string, 0, len(h.groups)+1), h.groups...), name)
+	return &h2
+}
+
+// Handler returns the Handler wrapped by h.
+func (h *SchemaHandler) Handler() slog.Handler { return h.handler }
@@ -0,0 +1,62 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+var _ = slog.Handler((*TapHandler)(nil))
+
+// TapHandler calls a callback with each record, before passing it to the underlying Handler.
+type TapHandler struct {
+	handler slog.Handler
+	f       func(slog.Record)
+}
+
+// NewTapHandler returns a TapHandler calling f with a clone of each record handled by h,
+// so f cannot corrupt the record seen by h.
+//
+// f is called synchronously, so it must be fast.
+func NewTapHandler(h slog.Handler, f func(slog.Record)) *TapHandler {
+	return &TapHandler{handler: h, f: f}
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *TapHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *TapHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.f(r.Clone())
+	return h.handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *TapHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return &TapHandler{handler: h.handler.WithAttrs(attrs), f: h.f}
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *TapHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &TapHandler{handler: h.handler.WithGroup(name), f: h.f}
+}
+
+// Handler returns the underlying Handler.
+func (h *TapHandler) Handler() slog.Handler { return h.handler }
+
+// WithSource returns a new TapHandler with the source toggled on the underlying Handler (if it supports it).
+func (h *TapHandler) WithSource(addSource bool) slog.Handler {
+	return &TapHandler{handler: withSource(h.handler, addSource), f: h.f}
+}
@@ -6,11 +6,19 @@ package zlog_test
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -138,6 +146,22 @@ func TestGroup(t *testing.T) {
 		}
 	})
 
+	t.Run("golden", func(t *testing.T) {
+		out := zlog.CaptureOutput(func(lgr zlog.Logger) { do(lgr.SLog()) })
+		t.Log(string(out))
+
+		const fakeAddr = "0xc000016c40"
+		rAddr := regexp.MustCompile("0x[0-9a-f]*")
+		const want = `00:00:00.000 INF "naked" a=0
+00:00:00.000 INF "justGroup" group.a=1
+00:00:00.000 INF "withValue" with=value a=2
+00:00:00.000 INF "withValueGroup" with=value group.a=3 group.func=` + fakeAddr + `
+`
+		if got := string(rAddr.ReplaceAll(out, []byte(fakeAddr))); got != want {
+			t.Errorf("got\n%s\nwanted\n%s", got, want)
+		}
+	})
+
 	t.Run("json", func(t *testing.T) {
 		var buf bytes.Buffer
 		zl := zlog.DefaultHandlerOptions.NewJSONHandler(&buf)
@@ -172,3 +196,1370 @@ func TestGroup(t *testing.T) {
 
 	})
 }
+
+func TestWithValuesIf(t *testing.T) {
+	for _, cond := range []bool{false, true} {
+		var buf bytes.Buffer
+		zlog.NewLogger(slog.NewJSONHandler(&buf, nil)).WithValuesIf(cond, "user", "u1").Info("info", "a", 1)
+		var m map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := m["user"]; ok != cond || (cond && m["user"] != "u1") {
+			t.Errorf("%t: got %v", cond, m)
+		}
+		if m["a"] != 1.0 {
+			t.Errorf("%t: got %v, wanted a=1", cond, m)
+		}
+	}
+}
+
+func TestWithComponent(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, nil)).WithComponent("db")
+	logger.Info("info", "a", 1)
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m[zlog.ComponentKey] != "db" {
+		t.Errorf("got %v, wanted component=db", m)
+	}
+	if m["a"] != 1.0 {
+		t.Errorf("got %v, wanted top-level a=1", m)
+	}
+}
+
+func TestErrorAttrsKeepsAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, nil))
+	attrs := make([]slog.Attr, 1, 2)
+	attrs[0] = slog.Int("a", 1)
+	spare := attrs[:2]
+	spare[1] = slog.String("b", "keep")
+	logger.ErrorAttrs(context.Background(), io.EOF, "error", attrs...)
+	if spare[1].Key != "b" {
+		t.Errorf("caller's backing array overwritten: %v", spare)
+	}
+	if got := buf.String(); !strings.Contains(got, `"a":1`) || !strings.Contains(got, `"error":"EOF"`) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	var bufA, bufB bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewMultiHandler(slog.NewJSONHandler(&bufA, nil), slog.NewJSONHandler(&bufB, nil)))
+	logger.Named("db").Info("info", "a", 1)
+	for _, buf := range []*bytes.Buffer{&bufA, &bufB} {
+		var m map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+			t.Fatal(err)
+		}
+		if m[zlog.LoggerNameKey] != "db" || m["a"] != 1.0 {
+			t.Errorf("got %v, wanted top-level logger=db a=1", m)
+		}
+	}
+
+	// not hoisted out of the group
+	var buf bytes.Buffer
+	zlog.NewLogger(slog.NewJSONHandler(&buf, nil)).WithGroup("g").Named("db").Info("info")
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if g, _ := m["g"].(map[string]any); g[zlog.LoggerNameKey] != "db" {
+		t.Errorf("got %v, wanted g.logger=db", m)
+	}
+}
+
+type panicHandler struct{ slog.Handler }
+
+func (h panicHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Message == "panic" {
+		panic("boom")
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+type recordingHandler struct {
+	slog.Handler
+	records *[]slog.Record
+}
+
+func (h recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	*h.records = append(*h.records, r)
+	return nil
+}
+
+func TestLoggerHandle(t *testing.T) {
+	var records []slog.Record
+	logger := zlog.NewLogger(recordingHandler{Handler: slog.NewJSONHandler(io.Discard, nil), records: &records})
+	pc, _, _, _ := runtime.Caller(0)
+	tm := time.Date(2024, 2, 29, 12, 34, 56, 0, time.UTC)
+	if err := logger.Handle(context.Background(), slog.NewRecord(tm, slog.LevelDebug, "disabled", pc)); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Handle(context.Background(), slog.NewRecord(tm, slog.LevelInfo, "replayed", pc)); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d records, wanted only the enabled one", len(records))
+	}
+	if r := records[0]; r.Message != "replayed" || !r.Time.Equal(tm) || r.PC != pc {
+		t.Errorf("got %q at %v from %x, wanted the time %v and PC %x unchanged", r.Message, r.Time, r.PC, tm, pc)
+	}
+}
+
+func TestRecoverHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewRecoverHandler(panicHandler{slog.NewJSONHandler(&buf, nil)}))
+	logger.Info("panic", "a", 1)
+	t.Log(buf.String())
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["msg"] != "log render panic" || m["message"] != "panic" || m["panic"] != "boom" {
+		t.Errorf("got %v", m)
+	}
+}
+
+func TestVerboseMapping(t *testing.T) {
+	var vv zlog.VerboseVar
+	if got := vv.Level(); got != slog.LevelWarn {
+		t.Errorf("default: got %v, wanted %v", got, slog.LevelWarn)
+	}
+	zlog.SetVerboseMapping(func(v uint8) slog.Level { return slog.LevelInfo - 4*slog.Level(v) })
+	defer zlog.SetVerboseMapping(nil)
+	if got := vv.Level(); got != slog.LevelInfo {
+		t.Errorf("custom: got %v, wanted %v", got, slog.LevelInfo)
+	}
+	vv = 1
+	if got := vv.Level(); got != slog.LevelDebug {
+		t.Errorf("custom: got %v, wanted %v", got, slog.LevelDebug)
+	}
+}
+
+func TestChromeTraceHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewChromeTraceHandler(&buf)
+	logger := zlog.NewLogger(h).WithValues("tid", 2)
+	logger.Info("begin", "ph", "B", "name", "load", "file", "a.txt")
+	logger.Info("ignored", "a", 1)
+	logger.Info("end", "ph", "E", "name", "load")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+	var events []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, wanted 2", len(events))
+	}
+	if events[0]["ph"] != "B" || events[0]["name"] != "load" || events[0]["tid"] != 2.0 {
+		t.Errorf("got %v", events[0])
+	}
+	if args, _ := events[0]["args"].(map[string]any); args["file"] != "a.txt" {
+		t.Errorf("got args %v", events[0]["args"])
+	}
+	if events[1]["ph"] != "E" {
+		t.Errorf("got %v", events[1])
+	}
+}
+
+func TestChromeTraceHandlerGroup(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewChromeTraceHandler(&buf)
+	logger := zlog.NewLogger(h).WithGroup("g").WithValues("tid", 3, "a", 1)
+	logger.Info("begin", "ph", "B", "name", "load", "file", "a.txt")
+	logger.Info("end", "ph", "E", "name", "load")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+	var events []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, wanted 2", len(events))
+	}
+	if events[0]["ph"] != "B" || events[0]["name"] != "load" || events[0]["tid"] != 3.0 {
+		t.Errorf("got %v", events[0])
+	}
+	args, _ := events[0]["args"].(map[string]any)
+	if g, _ := args["g"].(map[string]any); g["file"] != "a.txt" || g["a"] != 1.0 {
+		t.Errorf("got args %v", args)
+	}
+	if events[1]["ph"] != "E" {
+		t.Errorf("got %v", events[1])
+	}
+}
+
+func TestAuditLevel(t *testing.T) {
+	var bufJSON, bufConsole bytes.Buffer
+	var level slog.LevelVar
+	level.Set(zlog.AuditLevel + 4)
+	opts := zlog.DefaultHandlerOptions
+	logger := zlog.NewLogger(zlog.NewMultiHandler(
+		zlog.NewLevelHandler(&level, opts.NewJSONHandler(&bufJSON)),
+		zlog.NewConsoleHandler(&level, &bufConsole),
+	))
+	logger.Error(io.EOF, "error")
+	logger.Audit("audit", "user", "joe")
+	t.Log(bufJSON.String())
+	t.Log(bufConsole.String())
+	if !check(t, parse(bufJSON.Bytes()), map[string]int{"error": 0, "audit": 1}) {
+		return
+	}
+	if !check(t, parse(bufConsole.Bytes()), map[string]int{"error": 0, "audit": 1}) {
+		return
+	}
+	if recs := parse(bufJSON.Bytes()); recs["audit"][0].Level != "AUDIT" {
+		t.Errorf("got level %q, wanted AUDIT", recs["audit"][0].Level)
+	}
+	if !bytes.Contains(bufConsole.Bytes(), []byte("AUD")) {
+		t.Error("no AUD in console output")
+	}
+}
+
+func TestLoggerFlush(t *testing.T) {
+	var buf bytes.Buffer
+	bh := zlog.NewBatchingHandler(slog.NewJSONHandler(&buf, nil), 0, 100)
+	logger := zlog.NewLogger(zlog.NewLevelHandler(zlog.InfoLevel, zlog.NewMultiHandler(bh)))
+	logger.Info("info")
+	if buf.Len() != 0 {
+		t.Fatalf("got %q before Flush", buf.String())
+	}
+	if err := logger.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !check(t, parse(buf.Bytes()), map[string]int{"info": 1}) {
+		return
+	}
+}
+
+func TestTolerantMultiHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewTolerantMultiHandler(
+		panicHandler{slog.NewJSONHandler(io.Discard, nil)},
+		slog.NewJSONHandler(&buf, nil),
+	)
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "panic", 0)
+	if err := h.Handle(context.Background(), r); err == nil {
+		t.Error("wanted error from the panicking handler")
+	} else {
+		t.Log(err)
+	}
+	if !check(t, parse(buf.Bytes()), map[string]int{"panic": 1}) {
+		return
+	}
+}
+
+func TestLoggerClone(t *testing.T) {
+	var buf1, buf2 bytes.Buffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf1, nil))
+	clone := logger.Clone()
+	clone.SetHandler(slog.NewJSONHandler(&buf2, nil))
+	logger.Info("original")
+	clone.Info("clone")
+	if !check(t, parse(buf1.Bytes()), map[string]int{"original": 1, "clone": 0}) {
+		return
+	}
+	if !check(t, parse(buf2.Bytes()), map[string]int{"original": 0, "clone": 1}) {
+		return
+	}
+}
+
+func TestMaxAttrsHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewMaxAttrsHandler(slog.NewJSONHandler(&buf, nil), 2))
+	logger.Info("many", "a", 1, "b", 2, "c", 3, "d", 4)
+	logger.Info("many again", "a", 1, "b", 2, "c", 3)
+	t.Log(buf.String())
+	recs := parse(buf.Bytes())
+	if !check(t, recs, map[string]int{"too many attrs, truncating": 1, "many": 1, "many again": 1}) {
+		return
+	}
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	var m map[string]any
+	if err := json.Unmarshal(lines[1], &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["attrs_truncated"] != 2.0 || m["b"] != 2.0 || m["c"] != nil {
+		t.Errorf("got %v", m)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true, Level: zlog.DebugLevel}))
+	func() {
+		defer logger.Measure("measured", "a", 1)()
+		time.Sleep(time.Millisecond)
+	}()
+	t.Log(buf.String())
+	var m struct {
+		Duration time.Duration `json:"duration"`
+		Source   struct {
+			File string `json:"file"`
+		} `json:"source"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Duration < time.Millisecond {
+		t.Errorf("got duration %v, wanted at least 1ms", m.Duration)
+	}
+	if !strings.HasSuffix(m.Source.File, "handlers_test.go") {
+		t.Errorf("got source %q, wanted handlers_test.go", m.Source.File)
+	}
+}
+
+type detailedError struct{ error }
+
+func (de detailedError) Format(f fmt.State, verb rune) {
+	io.WriteString(f, de.Error())
+	if f.Flag('+') {
+		io.WriteString(f, "\nstack trace")
+	}
+}
+
+func TestErrorDetailHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewErrorDetailHandler(slog.NewJSONHandler(&buf, nil), 0))
+	logger.Error(detailedError{io.EOF}, "detailed")
+	logger.Error(io.EOF, "simple")
+	t.Log(buf.String())
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	for i, want := range []any{"EOF\nstack trace", nil} {
+		var m map[string]any
+		if err := json.Unmarshal(lines[i], &m); err != nil {
+			t.Fatal(err)
+		}
+		if m["error"] != "EOF" || m["error_detail"] != want {
+			t.Errorf("%d. got %v, wanted error_detail=%q", i, m, want)
+		}
+	}
+}
+
+func TestErrorAttrIsString(t *testing.T) {
+	var kinds []slog.Kind
+	opts := &slog.HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == zlog.ErrorKey {
+			kinds = append(kinds, a.Value.Kind())
+		}
+		return a
+	}}
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, opts))
+	logger.Error(io.EOF, "error")
+	logger.ErrorContext(context.Background(), io.EOF, "error context")
+	logger.ErrorAttrs(context.Background(), io.EOF, "error attrs")
+	t.Log(buf.String())
+	if len(kinds) != 3 {
+		t.Fatalf("got %d error attrs, wanted 3", len(kinds))
+	}
+	for i, k := range kinds {
+		if k != slog.KindString {
+			t.Errorf("%d. got %v, wanted %v", i, k, slog.KindString)
+		}
+	}
+}
+
+func TestBatchingHandlerInterval(t *testing.T) {
+	var buf bytes.Buffer
+	tickC := make(chan time.Time)
+	bh := zlog.NewBatchingHandler(slog.NewJSONHandler(&buf, nil), time.Hour, 100).
+		WithTicker(func(time.Duration) (<-chan time.Time, func()) { return tickC, func() {} })
+	logger := zlog.NewLogger(bh)
+	logger.Info("first")
+	logger.Info("second")
+	tickC <- time.Now()
+	// the second tick is received only after the first flush has finished
+	tickC <- time.Now()
+	close(tickC)
+	if !check(t, parse(buf.Bytes()), map[string]int{"first": 1, "second": 1}) {
+		return
+	}
+}
+
+func TestWithSource(t *testing.T) {
+	var bufJSON, bufConsole bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewMultiHandler(
+		zlog.DefaultHandlerOptions.NewJSONHandler(&bufJSON),
+		zlog.NewConsoleHandler(zlog.InfoLevel, &bufConsole),
+	))
+	logger.WithSource(false).Info("nosource")
+	logger.WithSource(true).Info("source")
+	t.Log(bufJSON.String())
+	t.Log(bufConsole.String())
+	lines := bytes.Split(bytes.TrimSpace(bufJSON.Bytes()), []byte{'\n'})
+	for i, want := range []bool{false, true} {
+		var m map[string]any
+		if err := json.Unmarshal(lines[i], &m); err != nil {
+			t.Fatal(err)
+		}
+		if _, got := m["source"]; got != want {
+			t.Errorf("%d. JSON source: got %t, wanted %t", i, got, want)
+		}
+	}
+	lines = bytes.Split(bytes.TrimSpace(bufConsole.Bytes()), []byte{'\n'})
+	for i, want := range []bool{false, true} {
+		if got := bytes.Contains(lines[i], []byte("handlers_test.go:")); got != want {
+			t.Errorf("%d. console source: got %t, wanted %t", i, got, want)
+		}
+	}
+}
+
+func TestEpochMillis(t *testing.T) {
+	var buf bytes.Buffer
+	opts := zlog.DefaultHandlerOptions
+	opts.EpochMillis = true
+	before := time.Now().UnixMilli()
+	zlog.NewLogger(opts.NewJSONHandler(&buf)).Info("millis")
+	t.Log(buf.String())
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["time"]; ok {
+		t.Errorf("got time in %v", m)
+	}
+	if ms, _ := m["ts_ms"].(float64); int64(ms) < before {
+		t.Errorf("got ts_ms=%v, wanted at least %d", m["ts_ms"], before)
+	}
+}
+
+func TestProbabilisticSamplingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewProbabilisticSamplingHandler(
+		slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: zlog.DebugLevel}),
+		slog.LevelInfo, 0.5))
+	const n = 1000
+	for i := 0; i < n; i++ {
+		logger.Debug("debug")
+		logger.Warn("warn")
+	}
+	recs := parse(buf.Bytes())
+	if got := len(recs["warn"]); got != n {
+		t.Errorf("got %d warn, wanted %d", got, n)
+	}
+	if got := len(recs["debug"]); got < n/4 || got > n*3/4 {
+		t.Errorf("got %d debug, wanted around %d", got, n/2)
+	}
+}
+
+func TestContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewContextAttrsHandler(slog.NewJSONHandler(&buf, nil)))
+	ctx := zlog.NewContextWithAttrs(context.Background(), slog.String("request_id", "abc"))
+	ctx = zlog.NewContextWithAttrs(ctx, slog.Int("user", 1))
+	logger.InfoContext(ctx, "ctx", "a", 1)
+	t.Log(buf.String())
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["request_id"] != "abc" || m["user"] != 1.0 || m["a"] != 1.0 {
+		t.Errorf("got %v", m)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	zlog.NewLogger(slog.NewJSONHandler(&buf, nil)).StdLogger(slog.LevelWarn).Print("std")
+	if recs := parse(buf.Bytes()); len(recs["std"]) != 1 || recs["std"][0].Level != "WARN" {
+		t.Errorf("got %v", recs)
+	}
+}
+
+func TestLogStartup(t *testing.T) {
+	var buf bytes.Buffer
+	zlog.NewLogger(slog.NewJSONHandler(&buf, nil)).LogStartup(slog.String("app", "test"))
+	t.Log(buf.String())
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"hostname", "pid", "args", "start", "go_version", "app"} {
+		if _, ok := m[k]; !ok {
+			t.Errorf("no %q in %v", k, m)
+		}
+	}
+}
+
+func TestRequireAttrsHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewRequireAttrsHandler(slog.NewJSONHandler(&buf, nil), "actor", "action")
+	logger := zlog.NewLogger(h).WithValues("actor", "joe")
+	logger.Info("complete", "action", "login")
+	logger.Info("incomplete")
+	t.Log(buf.String())
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	for i, want := range []any{nil, "action"} {
+		var m map[string]any
+		if err := json.Unmarshal(lines[i], &m); err != nil {
+			t.Fatal(err)
+		}
+		if m["missing_attrs"] != want {
+			t.Errorf("%d. got %v, wanted missing_attrs=%v", i, m, want)
+		}
+	}
+
+	h.Strict = true
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "strict", 0)); err == nil {
+		t.Error("wanted error in strict mode")
+	}
+}
+
+func TestLogAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true}))
+	logger.InfoAttrs(context.Background(), "attrs", slog.Int("a", 1))
+	logger.Info("args", "a", 1)
+	t.Log(buf.String())
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	for _, line := range lines {
+		var m struct {
+			A      int `json:"a"`
+			Source struct {
+				File string `json:"file"`
+			} `json:"source"`
+		}
+		if err := json.Unmarshal(line, &m); err != nil {
+			t.Fatal(err)
+		}
+		if m.A != 1 || !strings.HasSuffix(m.Source.File, "handlers_test.go") {
+			t.Errorf("got %+v", m)
+		}
+	}
+}
+
+func TestWithGroupEmpty(t *testing.T) {
+	base := slog.NewJSONHandler(io.Discard, nil)
+	for _, h := range []slog.Handler{
+		zlog.NewConsoleHandler(zlog.InfoLevel, io.Discard),
+		zlog.DefaultHandlerOptions.NewJSONHandler(io.Discard),
+		zlog.NewLevelHandler(zlog.InfoLevel, base),
+		zlog.NewMultiHandler(base),
+		zlog.NewBatchingHandler(base, 0, 0),
+		zlog.NewRecoverHandler(base),
+		zlog.NewMaxAttrsHandler(base, 1),
+		zlog.NewErrorDetailHandler(base, 0),
+		zlog.NewProbabilisticSamplingHandler(base, zlog.InfoLevel, 1),
+		zlog.NewContextAttrsHandler(base),
+		zlog.NewMultilineMessageHandler(base),
+		zlog.NewRequireAttrsHandler(base, "a"),
+		zlog.NewSchemaHandler(base, nil, nil),
+		zlog.NewChromeTraceHandler(io.Discard),
+	} {
+		if h2 := h.WithGroup(""); h2 != h {
+			t.Errorf("%T.WithGroup(\"\") returned a different handler", h)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, nil))
+	f := zlog.Fields{}
+	f.Str("k", "v").Int("n", 1)
+	if true {
+		f.Bool("b", true).Err(nil)
+	}
+	logger.Info("fields", f.Attrs()...)
+	t.Log(buf.String())
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["k"] != "v" || m["n"] != 1.0 || m["b"] != true || f.Len() != 3 {
+		t.Errorf("got %v", m)
+	}
+}
+
+func TestMultiBatchingWithAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	bh := zlog.NewBatchingHandler(slog.NewJSONHandler(&buf, nil), 0, 100)
+	logger := zlog.NewLogger(zlog.NewMultiHandler(bh))
+	logger.Info("first")
+	derived := logger.WithValues("with", "value").WithGroup("group")
+	if buf.Len() != 0 {
+		t.Fatalf("WithValues/WithGroup flushed: %q", buf.String())
+	}
+	derived.Info("second", "a", 1)
+	logger.Info("third")
+	if buf.Len() != 0 {
+		t.Fatalf("flushed before Flush: %q", buf.String())
+	}
+	if err := logger.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, wanted 3", len(lines))
+	}
+	for i, want := range []string{
+		`"msg":"first"}`,
+		`"msg":"second","with":"value","group":{"a":1}}`,
+		`"msg":"third"}`,
+	} {
+		if !bytes.HasSuffix(lines[i], []byte(want)) {
+			t.Errorf("%d. got %s, wanted %s", i, lines[i], want)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, want := range map[string]slog.Level{
+		"trace": zlog.TraceLevel, "DEBUG": slog.LevelDebug, "info": slog.LevelInfo,
+		"Warning": slog.LevelWarn, "error": slog.LevelError, "-4": slog.LevelDebug, "INFO+2": slog.LevelInfo + 2,
+		" -4\n": slog.LevelDebug, " debug ": slog.LevelDebug, "\tinfo+2 ": slog.LevelInfo + 2,
+	} {
+		if got, err := zlog.ParseLevel(s); err != nil {
+			t.Errorf("%q: %+v", s, err)
+		} else if got != want {
+			t.Errorf("%q: got %v, wanted %v", s, got, want)
+		}
+	}
+	if err := zlog.New(io.Discard).SetLevelString("verbose"); err == nil {
+		t.Error("wanted error for unknown level")
+	}
+}
+
+func TestBatchingHandlerFlushLevel(t *testing.T) {
+	var buf bytes.Buffer
+	bh := zlog.NewBatchingHandler(slog.NewJSONHandler(&buf, nil), 0, 100).WithFlushLevel(slog.LevelError)
+	logger := zlog.NewLogger(bh)
+	logger.Info("first")
+	logger.Info("second")
+	if buf.Len() != 0 {
+		t.Fatalf("flushed before Error: %q", buf.String())
+	}
+	logger.Error(errors.New("crash"), "third")
+	if !check(t, parse(buf.Bytes()), map[string]int{"first": 1, "second": 1, "third": 1}) {
+		return
+	}
+}
+
+func TestOnRecord(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.New(&buf)
+	var msgs []string
+	tapped := logger.OnRecord(func(r slog.Record) {
+		msgs = append(msgs, r.Message)
+		r.AddAttrs(slog.String("corrupt", "yes"))
+	})
+	tapped.Debug("filtered")
+	tapped.Info("first")
+	tapped.WithValues("a", 1).Info("second")
+	logger.Info("untapped")
+	if len(msgs) != 2 || msgs[0] != "first" || msgs[1] != "second" {
+		t.Errorf("got %q, wanted [first second]", msgs)
+	}
+	if strings.Contains(buf.String(), "corrupt") {
+		t.Errorf("callback corrupted the record: %s", buf.String())
+	}
+}
+
+func TestJSONArrayWriter(t *testing.T) {
+	var buf bytes.Buffer
+	aw := zlog.NewJSONArrayWriter(&buf)
+	bh := zlog.NewBatchingHandler(slog.NewJSONHandler(aw, nil), 0, 2)
+	logger := zlog.NewLogger(bh)
+	for _, msg := range []string{"first", "second", "third"} {
+		logger.Info(msg)
+	}
+	if err := logger.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+	var a []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &a); err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 3 || a[0]["msg"] != "first" || a[2]["msg"] != "third" {
+		t.Errorf("got %v", a)
+	}
+}
+
+func TestLoggerGroup(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, nil))
+	logger.Group("phase", func(logger zlog.Logger) {
+		logger.Info("inner", "a", 1)
+		logger.SetLevel(slog.LevelError)
+	})
+	logger.Info("outer", "a", 2)
+	t.Log(buf.String())
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, wanted 2", len(lines))
+	}
+	if want := `"msg":"inner","phase":{"a":1}}`; !bytes.HasSuffix(lines[0], []byte(want)) {
+		t.Errorf("got %s, wanted %s", lines[0], want)
+	}
+	if want := `"msg":"outer","a":2}`; !bytes.HasSuffix(lines[1], []byte(want)) {
+		t.Errorf("got %s, wanted %s", lines[1], want)
+	}
+}
+
+func TestHTMLHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := zlog.NewHTMLHandler(&buf)
+	logger := zlog.NewLogger(h).WithGroup("job")
+	logger.Info("done", "name", "<nightly>")
+	logger.Error(errors.New("bad & worse"), "failed")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+	got := buf.String()
+	for _, want := range []string{
+		"<table", "</table>\n",
+		`<tr style="color:blue"><td>`, `</td><td>INF</td><td>done</td><td>job.name=&lt;nightly&gt;</td></tr>`,
+		`<tr style="color:red"><td>`, `</td><td>ERR</td><td>failed</td><td>job.error=bad &amp; worse</td></tr>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q not in %q", want, got)
+		}
+	}
+}
+
+func TestLoggerWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := zlog.NewLogger(slog.NewJSONHandler(&buf, nil)).Writer(slog.LevelWarn, "child")
+	io.WriteString(w, "first\r\nsec")
+	io.WriteString(w, "ond\nthi")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, wanted 3", len(lines))
+	}
+	for i, want := range []string{"first", "second", "thi"} {
+		if suffix := `"level":"WARN","msg":"` + want + `","prefix":"child"}`; !bytes.HasSuffix(lines[i], []byte(suffix)) {
+			t.Errorf("%d. got %s, wanted %s", i, lines[i], suffix)
+		}
+	}
+}
+
+func TestLoggerSample(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, nil))
+	for i := 0; i < 25; i++ {
+		logger.Sample(10).Info("tick", "i", i)
+	}
+	if !check(t, parse(buf.Bytes()), map[string]int{"tick": 3}) {
+		return
+	}
+}
+
+func TestErrorKey(t *testing.T) {
+	defer func(old string) { zlog.ErrorKey = old }(zlog.ErrorKey)
+	zlog.ErrorKey = "err"
+	var buf bytes.Buffer
+	zlog.NewLogger(slog.NewJSONHandler(&buf, nil)).Error(errors.New("bad"), "failed")
+	if want := `"msg":"failed","err":"bad"}`; !strings.Contains(buf.String(), want) {
+		t.Errorf("got %s, wanted %s", buf.String(), want)
+	}
+}
+
+func TestMergeContextAttrs(t *testing.T) {
+	ctx1 := zlog.NewContextWithAttrs(context.Background(), slog.String("tenant", "a"), slog.Int("item", 1))
+	ctx2 := zlog.NewContextWithAttrs(context.Background(), slog.Int("item", 2), slog.String("trace", "x"))
+	attrs := zlog.MergeContextAttrs(ctx1, nil, context.Background(), ctx2)
+	if got, want := fmt.Sprint(attrs), "[tenant=a item=2 trace=x]"; got != want {
+		t.Errorf("got %s, wanted %s", got, want)
+	}
+}
+
+func TestPerLevelFileHandler(t *testing.T) {
+	dir := t.TempDir()
+	h := zlog.NewPerLevelFileHandler(dir, nil)
+	logger := zlog.NewLogger(h).WithValues("a", 1)
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error(errors.New("bad"), "error")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if got, want := strings.Join(names, ","), "error.log,info.log,warn.log"; got != want {
+		t.Errorf("got %s, wanted %s", got, want)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "warn.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b, []byte(`"msg":"warn"`)) || !bytes.Contains(b, []byte(`"a":1`)) || bytes.Contains(b, []byte(`"msg":"info"`)) {
+		t.Errorf("warn.log: %s", b)
+	}
+
+	dir = t.TempDir()
+	h = zlog.NewPerLevelFileHandler(dir, &zlog.PerLevelFileOptions{Level: zlog.TraceLevel})
+	zlog.NewLogger(h).Debug("debug")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = os.ReadFile(filepath.Join(dir, "debug.log")); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Contains(b, []byte(`"msg":"debug"`)) {
+		t.Errorf("debug.log: %q", b)
+	}
+}
+
+func TestPerLevelFileHandlerClosed(t *testing.T) {
+	h := zlog.NewPerLevelFileHandler(t.TempDir(), nil)
+	derived := h.WithAttrs([]slog.Attr{slog.Int("a", 1)})
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "before", 0)
+	if err := derived.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r = slog.NewRecord(time.Now(), slog.LevelInfo, "after", 0)
+	for _, hndl := range []slog.Handler{h, derived} {
+		if err := hndl.Handle(context.Background(), r); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("%T.Handle after Close: got %v, wanted %v", hndl, err, os.ErrClosed)
+		}
+	}
+}
+
+func TestSetLevelMulti(t *testing.T) {
+	var console, errs bytes.Buffer
+	consoleH := zlog.NewLevelHandler(&slog.LevelVar{}, slog.NewJSONHandler(&console, nil))
+	errsH := zlog.NewLevelHandler(slog.LevelError, slog.NewJSONHandler(&errs, nil))
+	logger := zlog.NewLogger(zlog.NewMultiHandler(consoleH, errsH))
+
+	logger.SetLevel(slog.LevelDebug)
+	if !consoleH.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("console: debug is not enabled after SetLevel(Debug)")
+	}
+	if errsH.Enabled(context.Background(), slog.LevelInfo) || !errsH.Enabled(context.Background(), slog.LevelWarn) {
+		t.Errorf("errors: got level %v, wanted Warn (offset kept)", errsH.GetLevel())
+	}
+
+	logger.SetLevelAll(slog.LevelInfo)
+	for _, lh := range []*zlog.LevelHandler{consoleH, errsH} {
+		if got := lh.GetLevel().Level(); got != slog.LevelInfo {
+			t.Errorf("got %v, wanted Info", got)
+		}
+	}
+	logger.Debug("no")
+	logger.Info("yes")
+	if !strings.Contains(errs.String(), `"msg":"yes"`) || strings.Contains(console.String(), `"msg":"no"`) {
+		t.Errorf("got console=%q errors=%q", console.String(), errs.String())
+	}
+}
+
+func TestCatch(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true}))
+	func() {
+		defer logger.Catch(false)
+		panic("boom")
+	}()
+	t.Log(buf.String())
+	var m struct {
+		Source struct {
+			Function string `json:"function"`
+		} `json:"source"`
+		Panic string   `json:"panic"`
+		Stack []string `json:"stack"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Panic != "boom" || len(m.Stack) == 0 || !strings.Contains(m.Stack[0], "TestCatch") ||
+		!strings.Contains(m.Source.Function, "TestCatch") {
+		t.Errorf("got %+v", m)
+	}
+
+	defer func() {
+		if p := recover(); p != "again" {
+			t.Errorf("got %v, wanted the re-panic", p)
+		}
+	}()
+	defer logger.Catch(true)
+	panic("again")
+}
+
+func TestBackoffDedupHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewBackoffDedupHandler(slog.NewJSONHandler(&buf, nil), nil))
+	for i := 0; i < 10; i++ {
+		logger.Error(errors.New("connection refused"), "dial")
+	}
+	logger.Error(errors.New("other"), "dial")
+	t.Log(buf.String())
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, wanted 5 (1st, 2nd, 4th, 8th and the other)", len(lines))
+	}
+	if want := `"count":8,"suppressed":3}`; !strings.HasSuffix(lines[3], want) {
+		t.Errorf("got %s, wanted suffix %s", lines[3], want)
+	}
+	if strings.Contains(lines[4], "count") {
+		t.Errorf("the first occurrence should not have a count: %s", lines[4])
+	}
+}
+
+func TestRecordSeparator(t *testing.T) {
+	for _, tc := range []struct{ sep, want string }{
+		{"", "{a}\n{b}\n"},
+		{"\x00", "{a}\x00{b}\x00"},
+		{zlog.JSONSeqSeparator, "\x1e{a}\n\x1e{b}\n"},
+	} {
+		var buf bytes.Buffer
+		opts := zlog.HandlerOptions{RecordSeparator: tc.sep}
+		logger := zlog.NewLogger(opts.NewJSONHandler(&buf))
+		logger.Info("a")
+		logger.Info("b")
+		re := regexp.MustCompile(`\{[^}]*"msg":"(.)"\}`)
+		if got := re.ReplaceAllString(buf.String(), "{$1}"); got != tc.want {
+			t.Errorf("%q: got %q, wanted %q", tc.sep, got, tc.want)
+		}
+	}
+}
+
+func TestContextLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewLevelHandler(slog.LevelInfo, slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: zlog.TraceLevel})))
+	debugCtx := zlog.NewContextLevel(context.Background(), slog.LevelDebug)
+	errorCtx := zlog.NewContextLevel(context.Background(), slog.LevelError)
+
+	logger.Debug("no")
+	logger.DebugContext(debugCtx, "debug")
+	logger.InfoContext(errorCtx, "info")
+	if got, want := buf.String(), `"msg":"debug"`; strings.Contains(got, `"msg":"no"`) || !strings.Contains(got, want) ||
+		!strings.Contains(got, `"msg":"info"`) {
+		t.Errorf("got %q, wanted only the debug and info records", got)
+	}
+
+	defer func(old bool) { zlog.ContextLevelCanRaise = old }(zlog.ContextLevelCanRaise)
+	zlog.ContextLevelCanRaise = true
+	buf.Reset()
+	logger.InfoContext(errorCtx, "raised")
+	if buf.Len() != 0 {
+		t.Errorf("got %q, wanted nothing", buf.String())
+	}
+}
+
+type slowHandler struct {
+	slog.Handler
+	delay time.Duration
+}
+
+func (h slowHandler) Handle(ctx context.Context, r slog.Record) error {
+	time.Sleep(h.delay)
+	return h.Handler.Handle(ctx, r)
+}
+
+func TestTimedHandler(t *testing.T) {
+	var slow []time.Duration
+	sh := slowHandler{Handler: slog.NewJSONHandler(io.Discard, nil)}
+	logger := zlog.NewLogger(zlog.NewTimedHandler(&sh, 10*time.Millisecond, func(d time.Duration) { slow = append(slow, d) }))
+	logger.Info("fast")
+	sh.delay = 20 * time.Millisecond
+	logger.Info("slow")
+	if len(slow) != 1 || slow[0] < 20*time.Millisecond {
+		t.Errorf("got %v, wanted one slow call", slow)
+	}
+}
+
+func TestWithCurrentSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no symlinks on Windows")
+	}
+	dir := t.TempDir()
+	link := filepath.Join(dir, "app.log")
+	open := zlog.WithCurrentSymlink(link, func(path string) (io.WriteCloser, error) { return os.Create(path) })
+	for _, name := range []string{"app-1.log", "app-2.log"} {
+		fh, err := open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fh.Close()
+		if got, err := os.Readlink(link); err != nil || got != name {
+			t.Errorf("got %q (%+v), wanted %q", got, err, name)
+		}
+	}
+}
+
+func TestKeyNormalizeHandler(t *testing.T) {
+	for in, want := range map[string]string{
+		"userID": "user_id", "HTTPStatus": "http_status", "snake_case": "snake_case",
+		"fooBar2Baz": "foo_bar2_baz", "A": "a", "already_Snake": "already_snake",
+	} {
+		if got := zlog.SnakeCase(in); got != want {
+			t.Errorf("SnakeCase(%q): got %q, wanted %q", in, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewKeyNormalizeHandler(slog.NewJSONHandler(&buf, nil), nil)).SLog()
+	logger.With("requestID", 1).WithGroup("httpReq").Info("msg",
+		slog.Group("remoteAddr", "ipAddr", "::1", slog.Group("geoInfo", "countryCode", "HU")))
+	t.Log(buf.String())
+	if want := `"msg":"msg","request_id":1,"http_req":{"remote_addr":{"ip_addr":"::1","geo_info":{"country_code":"HU"}}}}`; !strings.HasSuffix(strings.TrimSpace(buf.String()), want) {
+		t.Errorf("got %s, wanted suffix %s", buf.String(), want)
+	}
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (lb *lockedBuffer) Write(p []byte) (int, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return lb.buf.Write(p)
+}
+func (lb *lockedBuffer) Bytes() []byte {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return append([]byte(nil), lb.buf.Bytes()...)
+}
+
+func TestGzipWriter(t *testing.T) {
+	var buf lockedBuffer
+	gw := zlog.NewGzipWriter(&buf)
+	gw.FlushInterval = 10 * time.Millisecond
+	logger := zlog.NewLogger(slog.NewJSONHandler(gw, nil))
+	logger.Info("first")
+	time.Sleep(50 * time.Millisecond)
+
+	// the flushed, unterminated stream is readable
+	zr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(zr)
+	if !bytes.Contains(b, []byte(`"msg":"first"`)) {
+		t.Errorf("got %q before Close", b)
+	}
+
+	logger.Info("second")
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if zr, err = gzip.NewReader(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = io.ReadAll(zr); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b, []byte(`"msg":"second"`)) {
+		t.Errorf("got %q after Close", b)
+	}
+}
+
+func TestMonotonicHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(zlog.NewMonotonicHandler(slog.NewJSONHandler(&buf, nil)))
+	logger.Info("first")
+	time.Sleep(time.Millisecond)
+	logger.Info("second")
+	dec := json.NewDecoder(&buf)
+	var monos [2]int64
+	for i := range monos {
+		var m struct {
+			Mono int64 `json:"mono_ns"`
+		}
+		if err := dec.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		monos[i] = m.Mono
+	}
+	if d := time.Duration(monos[1] - monos[0]); monos[0] <= 0 || d < time.Millisecond {
+		t.Errorf("got %v, wanted increasing timestamps at least 1ms apart", monos)
+	}
+}
+
+func TestLazyLogger(t *testing.T) {
+	var built int
+	var buf bytes.Buffer
+	logger := zlog.NewLazyLogger(func() slog.Handler {
+		built++
+		return slog.NewJSONHandler(&buf, nil)
+	})
+	derived := logger.WithValues("a", 1)
+	if built != 0 {
+		t.Fatal("built before the first use")
+	}
+	derived.Info("first")
+	logger.Info("second")
+	if built != 1 {
+		t.Errorf("built %d times, wanted once", built)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"msg":"first","a":1}`)) || !bytes.Contains(buf.Bytes(), []byte(`"msg":"second"}`)) {
+		t.Errorf("got %s", buf.String())
+	}
+}
+
+func TestLazyLoggerNotBuiltByWalkers(t *testing.T) {
+	var buf bytes.Buffer
+	var built int
+	logger := zlog.NewLazyLogger(func() slog.Handler {
+		built++
+		return slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	})
+	logger.SetLevel(slog.LevelInfo)
+	v := logger.V(1)
+	v.SetLevel(slog.LevelWarn)
+	if err := zlog.NewLazyLogger(func() slog.Handler { built++; return nil }).Close(); err != nil {
+		t.Fatal(err)
+	}
+	var vBuf bytes.Buffer
+	lazyV := zlog.NewLazyLogger(func() slog.Handler {
+		built++
+		return slog.NewJSONHandler(&vBuf, nil)
+	}).V(4)
+	if built != 0 {
+		t.Fatalf("built %d times by SetLevel, V or Close", built)
+	}
+	logger.Debug("not logged")
+	logger.Info("logged")
+	v.Info("not logged by v")
+	v.Warn("logged by v")
+	if built != 1 {
+		t.Errorf("built %d times, wanted once", built)
+	}
+	lazyV.Debug("debug by lazy v")
+	if built != 2 || !strings.Contains(vBuf.String(), `"msg":"debug by lazy v"`) {
+		t.Errorf("built %d times, got %s", built, vBuf.String())
+	}
+	if got := buf.String(); strings.Contains(got, "not logged") ||
+		!strings.Contains(got, `"msg":"logged"`) || !strings.Contains(got, `"msg":"logged by v"`) {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestLoggerClose(t *testing.T) {
+	var buf bytes.Buffer
+	hh := zlog.NewHTMLHandler(&buf)
+	logger := zlog.NewLogger(zlog.NewLevelHandler(slog.LevelInfo,
+		zlog.NewMultiHandler(slog.NewJSONHandler(io.Discard, nil), hh)))
+	logger.Info("closing")
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(buf.String(), "</table>\n") {
+		t.Errorf("HTMLHandler is not closed: %q", buf.String())
+	}
+	if err := zlog.NewLogger(slog.NewJSONHandler(io.Discard, nil)).Close(); err != nil {
+		t.Errorf("in-memory: %+v", err)
+	}
+
+	// the batching handler's periodic flusher is stopped by Close
+	var batched lockedBuffer
+	tickC := make(chan time.Time)
+	stopped := make(chan struct{})
+	bh := zlog.NewBatchingHandler(slog.NewJSONHandler(&batched, nil), time.Hour, 100).
+		WithTicker(func(time.Duration) (<-chan time.Time, func()) { return tickC, func() { close(stopped) } })
+	logger = zlog.NewLogger(bh)
+	logger.Info("batched")
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(batched.Bytes(), []byte(`"msg":"batched"`)) {
+		t.Errorf("batch is not flushed by Close: %q", batched.Bytes())
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Error("the periodic flusher is not stopped by Close")
+	}
+	logger.Info("after close")
+	select {
+	case tickC <- time.Now():
+		t.Error("the periodic flusher is still running after Close")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if bytes.Contains(batched.Bytes(), []byte("after close")) {
+		t.Errorf("flushed after Close: %q", batched.Bytes())
+	}
+}
+
+func TestSchemaHandler(t *testing.T) {
+	var buf bytes.Buffer
+	var violations []string
+	h := zlog.NewSchemaHandler(slog.NewJSONHandler(&buf, nil),
+		map[string]bool{"a": true, "g": true, "g.b": true},
+		func(key string) { violations = append(violations, key) })
+	logger := slog.New(h).With("a", 1, "x", 2)
+	logger.Info("top", "y", 3, slog.Group("g", "b", 4, "c", 5))
+	h.Recurse = true
+	slog.New(h).WithGroup("g").Info("recurse", "b", 6, "c", 7)
+	t.Log(buf.String())
+	if got, want := strings.Join(violations, ","), "x,y,g.c"; got != want {
+		t.Errorf("got violations %q, wanted %q", got, want)
+	}
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	for i, want := range []string{
+		`"a":1,"g":{"b":4,"c":5}}`,
+		`"g":{"b":6}}`,
+	} {
+		if !bytes.HasSuffix(lines[i], []byte(want)) {
+			t.Errorf("%d. got %s, wanted suffix %s", i, lines[i], want)
+		}
+	}
+}
+
+func TestVWithoutLevelHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	logger.V(1).Info("info at V(1)")
+	if buf.Len() != 0 {
+		t.Errorf("V(1) of a Warn logger logged Info: %s", buf.String())
+	}
+	logger.V(int(slog.LevelWarn - slog.LevelInfo)).Info("info at V(4)")
+	if !strings.Contains(buf.String(), "info at V(4)") {
+		t.Errorf("V(4) of a Warn logger did not log Info: %s", buf.String())
+	}
+	buf.Reset()
+	logger.V(1).Debug("debug at V(1)")
+	if buf.Len() != 0 {
+		t.Errorf("V(1) of a Warn logger logged Debug: %s", buf.String())
+	}
+}
+
+func TestVMultiHandler(t *testing.T) {
+	var warnBuf, debugBuf bytes.Buffer
+	opts := &slog.HandlerOptions{Level: zlog.TraceLevel}
+	logger := zlog.NewLogger(zlog.NewMultiHandler(
+		zlog.NewLevelHandler(slog.LevelWarn, slog.NewJSONHandler(&warnBuf, opts)),
+		zlog.NewLevelHandler(slog.LevelDebug, slog.NewJSONHandler(&debugBuf, opts)),
+	))
+	// the base level is the lowest of the children, not the first child's
+	logger.V(1).Debug("debug at V(1)")
+	if warnBuf.Len() != 0 {
+		t.Errorf("the Warn sink logged Debug: %s", warnBuf.String())
+	}
+	if !strings.Contains(debugBuf.String(), "debug at V(1)") {
+		t.Errorf("the Debug sink did not log Debug at V(1): %q", debugBuf.String())
+	}
+}
+
+func TestBatchingHandlerBatchFormat(t *testing.T) {
+	var w countingWriter
+	format := func(records []slog.Record) ([]byte, error) {
+		type line struct {
+			Msg   string         `json:"msg"`
+			Attrs map[string]any `json:"attrs"`
+		}
+		lines := make([]line, 0, len(records))
+		for _, r := range records {
+			attrs := make(map[string]any)
+			r.Attrs(func(a slog.Attr) bool { attrs[a.Key] = a.Value.Resolve().Any(); return true })
+			lines = append(lines, line{Msg: r.Message, Attrs: attrs})
+		}
+		return json.Marshal(map[string]any{"streams": lines})
+	}
+	bh := zlog.NewBatchingHandler(slog.NewJSONHandler(io.Discard, nil), 0, 2).WithBatchFormat(&w, format)
+	logger := slog.New(bh).With("app", "test").WithGroup("g")
+	logger.Info("first", "a", 1)
+	logger.Info("second")
+	t.Log(w.String())
+	if w.writes != 1 {
+		t.Errorf("got %d writes, wanted 1", w.writes)
+	}
+	var got struct {
+		Streams []struct {
+			Msg   string         `json:"msg"`
+			Attrs map[string]any `json:"attrs"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(w.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Streams) != 2 || got.Streams[0].Msg != "first" || got.Streams[1].Msg != "second" {
+		t.Fatalf("got %+v", got)
+	}
+	if got.Streams[0].Attrs["app"] != "test" || got.Streams[1].Attrs["app"] != "test" {
+		t.Errorf("missing the WithAttrs attr: %+v", got)
+	}
+	if _, ok := got.Streams[0].Attrs["g"]; !ok {
+		t.Errorf("missing the group: %+v", got)
+	}
+}
+
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) { w.writes++; return w.Buffer.Write(p) }
+
+func TestSampleErrors(t *testing.T) {
+	var buf lockedBuffer
+	lines := func() []string { return strings.Split(strings.TrimSpace(string(buf.Bytes())), "\n") }
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, nil)).SampleErrors(50 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		logger.Error(errors.New("connection refused"), "dial", "i", i)
+		logger.Info("tick")
+	}
+	logger.Error(errors.New("other"), "dial")
+	// the storm stops: the summary is written at the end of the window, then the counters are dropped
+	time.Sleep(150 * time.Millisecond)
+	t.Log(string(buf.Bytes()))
+	got := lines()
+	if len(got) != 5+3 {
+		t.Fatalf("got %d lines, wanted 8 (5 Info, 2 first Errors and the summary)", len(got))
+	}
+	if strings.Contains(got[0], "count") {
+		t.Errorf("the first occurrence should not have a count: %s", got[0])
+	}
+	if want := `"i":4,"error":"connection refused","count":5,"suppressed":3}`; !strings.HasSuffix(got[7], want) {
+		t.Errorf("got %s, wanted suffix %s", got[7], want)
+	}
+
+	logger.Error(errors.New("connection refused"), "redial")
+	if got := lines(); strings.Contains(got[len(got)-1], "count") {
+		t.Errorf("the counters are not dropped after a quiet window: %s", got[len(got)-1])
+	}
+	logger.Error(errors.New("connection refused"), "redial", "i", 1)
+	logger.Error(errors.New("connection refused"), "redial", "i", 2)
+	if err := logger.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := lines(), `"i":2,"error":"connection refused","count":3,"suppressed":1}`; len(got) != 10 || !strings.HasSuffix(got[9], want) {
+		t.Errorf("got %q, wanted the Flushed summary with suffix %s", got, want)
+	}
+}
+
+func TestVSetLevelKeepsParent(t *testing.T) {
+	var buf bytes.Buffer
+	var level slog.LevelVar
+	logger := zlog.NewLogger(zlog.NewLevelHandler(&level, slog.NewJSONHandler(&buf, nil)))
+	child := logger.V(1).WithValues("child", true)
+	child.SetLevel(slog.LevelError)
+	if got := level.Level(); got != slog.LevelInfo {
+		t.Errorf("V(1).SetLevel modified the parent's level to %v", got)
+	}
+	logger.Info("parent")
+	child.Info("child")
+	if !strings.Contains(buf.String(), `"msg":"parent"`) || strings.Contains(buf.String(), `"msg":"child"`) {
+		t.Errorf("got %q", buf.String())
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+var _ = slog.Handler((*TimedHandler)(nil))
+
+// TimedHandler measures the duration of the underlying Handler's Handle,
+// and calls a callback when it exceeds a threshold - for detecting a misbehaving (network) sink.
+type TimedHandler struct {
+	handler   slog.Handler
+	onSlow    func(time.Duration)
+	threshold time.Duration
+}
+
+// NewTimedHandler returns a TimedHandler calling onSlow with the duration of each h.Handle call
+// longer than threshold.
+//
+// onSlow is called synchronously, after Handle returned; it must not log to h (that would recurse).
+func NewTimedHandler(h slog.Handler, threshold time.Duration, onSlow func(time.Duration)) *TimedHandler {
+	return &TimedHandler{handler: h, threshold: threshold, onSlow: onSlow}
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *TimedHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *TimedHandler) Handle(ctx context.Context, r slog.Record) error {
+	start := time.Now()
+	err := h.handler.Handle(ctx, r)
+	if dur := time.Since(start); dur > h.threshold && h.onSlow != nil {
+		h.onSlow(dur)
+	}
+	return err
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *TimedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return &TimedHandler{handler: h.handler.WithAttrs(attrs), threshold: h.threshold, onSlow: h.onSlow}
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *TimedHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &TimedHandler{handler: h.handler.WithGroup(name), threshold: h.threshold, onSlow: h.onSlow}
+}
+
+// Handler returns the underlying Handler.
+func (h *TimedHandler) Handler() slog.Handler { return h.handler }
+
+// WithSource returns a new TimedHandler with the source toggled on the underlying Handler (if it supports it).
+func (h *TimedHandler) WithSource(addSource bool) slog.Handler {
+	return &TimedHandler{handler: withSource(h.handler, addSource), threshold: h.threshold, onSlow: h.onSlow}
+}
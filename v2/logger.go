@@ -8,11 +8,17 @@ package zlog
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log"
+	"math"
 	"runtime"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -21,6 +27,10 @@ import (
 )
 
 // Logger is a helper type for logr.Logger -like slog.Logger.
+//
+// Plain copies of a Logger share the underlying state,
+// so SetOutput/SetHandler/SetLevel on a copy modifies the original, too.
+// Use Clone for an independent Logger.
 type Logger struct{ p *atomic.Pointer[slog.Logger] }
 
 func newLogger() Logger { return Logger{p: &atomic.Pointer[slog.Logger]{}} }
@@ -35,6 +45,14 @@ func (lgr Logger) load() *slog.Logger {
 	return discard()
 }
 
+// Clone returns an independent Logger, initialized to the current handler of lgr,
+// thus SetOutput/SetHandler on the clone does not affect lgr.
+func (lgr Logger) Clone() Logger {
+	lgr2 := newLogger()
+	lgr2.p.Store(lgr.load())
+	return lgr2
+}
+
 // Discard returns a Logger that does not log at all.
 func Discard() Logger {
 	lgr := newLogger()
@@ -112,16 +130,215 @@ func (lgr Logger) log(ctx context.Context, level slog.Level, msg string, args ..
 	if !l.Enabled(ctx, level) {
 		return
 	}
+	r := newRecord(level, msg)
+	lgr.checkKV(r.PC, args)
+	r.Add(args...)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_ = l.Handler().Handle(ctx, r)
+}
+
+func (lgr Logger) logAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	l := lgr.load()
+	if !l.Enabled(ctx, level) {
+		return
+	}
+	r := newRecord(level, msg)
+	r.AddAttrs(attrs...)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_ = l.Handler().Handle(ctx, r)
+}
+
+// newRecord returns a new Record with the source of the caller of the log/logAttrs's caller.
+func newRecord(level slog.Level, msg string) slog.Record {
 	var pcs [1]uintptr
 	// https://pkg.go.dev/log/slog#example-package-Wrapping
+	// skip [runtime.Callers, this function, log/logAttrs, their caller]
+	runtime.Callers(4, pcs[:])
+	return slog.NewRecord(time.Now(), level, msg, pcs[0])
+}
+
+// KVCheck is the mode of checking the key-value lists (args) passed to the Logger.
+type KVCheck uint8
+
+const (
+	// KVLenient does not check the key-value lists: the malformed ones produce !BADKEY attrs, as in slog.
+	KVLenient = KVCheck(iota)
+	// KVWarn logs a warning (with the source of the caller) for each malformed key-value list.
+	KVWarn
+	// KVPanic panics on a malformed key-value list.
+	KVPanic
+)
+
+// StrictKV is the mode of checking the key-value lists of Info, Error, WithValues... (KVLenient by default).
+//
+// A key-value list is malformed if it has a key without value, or a non-string key (which is not an slog.Attr),
+// which slog would render as a !BADKEY attr. Meant for development:
+//
+//	zlog.StrictKV = zlog.KVPanic
+var StrictKV = KVLenient
+
+// checkKV checks the key-value list according to StrictKV, reporting the source of pc.
+func (lgr Logger) checkKV(pc uintptr, args []any) {
+	if StrictKV == KVLenient || !malformedKV(args) {
+		return
+	}
+	if StrictKV == KVPanic {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		panic(fmt.Sprintf("zlog: malformed key-value list at %s:%d: %v", frame.File, frame.Line, args))
+	}
+	ctx := context.Background()
+	h := lgr.load().Handler()
+	if !h.Enabled(ctx, slog.LevelWarn) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), slog.LevelWarn, "malformed key-value list", pc)
+	r.AddAttrs(slog.Int("len", len(args)))
+	_ = h.Handle(ctx, r)
+}
+
+// malformedKV reports whether the args would produce a !BADKEY attr.
+func malformedKV(args []any) bool {
+	for i := 0; i < len(args); i++ {
+		switch args[i].(type) {
+		case slog.Attr:
+		case string:
+			if i == len(args)-1 {
+				return true
+			}
+			i++
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// callerPC returns the pc of the caller of the caller of callerPC.
+func callerPC() uintptr {
+	var pcs [1]uintptr
+	// skip [runtime.Callers, this function, its caller]
+	runtime.Callers(3, pcs[:])
+	return pcs[0]
+}
+
+// LogAttrs logs the attrs at the given level, if enabled.
+func (lgr Logger) LogAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	lgr.logAttrs(ctx, level, msg, attrs...)
+}
+
+// DebugAttrs logs the attrs at DebugLevel, if enabled.
+func (lgr Logger) DebugAttrs(ctx context.Context, msg string, attrs ...slog.Attr) {
+	lgr.logAttrs(ctx, slog.LevelDebug, msg, attrs...)
+}
+
+// InfoAttrs logs the attrs at InfoLevel, if enabled.
+func (lgr Logger) InfoAttrs(ctx context.Context, msg string, attrs ...slog.Attr) {
+	lgr.logAttrs(ctx, slog.LevelInfo, msg, attrs...)
+}
+
+// WarnAttrs logs the attrs at WarnLevel, if enabled.
+func (lgr Logger) WarnAttrs(ctx context.Context, msg string, attrs ...slog.Attr) {
+	lgr.logAttrs(ctx, slog.LevelWarn, msg, attrs...)
+}
+
+// ErrorAttrs logs the attrs and the error at ErrorLevel, if enabled.
+func (lgr Logger) ErrorAttrs(ctx context.Context, err error, msg string, attrs ...slog.Attr) {
+	lgr.logAttrs(ctx, slog.LevelError, msg, append(slices.Clip(attrs), errorAttr(err))...)
+}
+
+// Measure returns a function which logs msg with the elapsed "duration" at DebugLevel,
+// with the source of the caller of Measure.
+//
+//	defer logger.Measure("load config")()
+func (lgr Logger) Measure(msg string, args ...any) func() {
+	return lgr.measure(slog.LevelDebug, msg, args)
+}
+
+// MeasureLevel is like Measure, but logs at the given level.
+func (lgr Logger) MeasureLevel(level slog.Level, msg string, args ...any) func() {
+	return lgr.measure(level, msg, args)
+}
+
+func (lgr Logger) measure(level slog.Level, msg string, args []any) func() {
+	var pcs [1]uintptr
 	// skip [runtime.Callers, this function, this function's caller]
 	runtime.Callers(3, pcs[:])
-	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
-	r.Add(args...)
+	start := time.Now()
+	return func() {
+		dur := time.Since(start)
+		l := lgr.load()
+		ctx := context.Background()
+		if !l.Enabled(ctx, level) {
+			return
+		}
+		r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+		r.Add(args...)
+		r.AddAttrs(slog.Duration("duration", dur))
+		_ = l.Handler().Handle(ctx, r)
+	}
+}
+
+// WithDeadline returns a function which logs msg at WarnLevel with the "overrun" duration (and the "deadline"),
+// iff it is called later than d after WithDeadline, with the source of the caller of WithDeadline.
+//
+//	done := logger.WithDeadline(ctx, 200*time.Millisecond, "db query")
+//	defer done()
+//
+// When the deadline passes before done is called, msg is logged with "running"=true, too,
+// so a call which never finishes is reported.
+// That timer is stopped by done or when ctx is done, so nothing leaks if done is never called.
+//
+// The records are logged with ctx (for its attrs). Only the first call of done counts.
+func (lgr Logger) WithDeadline(ctx context.Context, d time.Duration, msg string, args ...any) (done func()) {
+	var pcs [1]uintptr
+	// skip [runtime.Callers, this function]
+	runtime.Callers(2, pcs[:])
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	_ = l.Handler().Handle(ctx, r)
+	log := func(attrs ...slog.Attr) {
+		l := lgr.load()
+		if !l.Enabled(ctx, slog.LevelWarn) {
+			return
+		}
+		r := slog.NewRecord(time.Now(), slog.LevelWarn, msg, pcs[0])
+		r.Add(args...)
+		r.AddAttrs(slog.Duration("deadline", d))
+		r.AddAttrs(attrs...)
+		_ = l.Handler().Handle(ctx, r)
+	}
+	deadline := time.Now().Add(d)
+	timer := time.AfterFunc(d, func() { log(slog.Bool("running", true)) })
+	stopCtx := context.AfterFunc(ctx, func() { timer.Stop() })
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			timer.Stop()
+			stopCtx()
+			if overrun := time.Since(deadline); overrun > 0 {
+				log(slog.Duration("overrun", overrun))
+			}
+		})
+	}
+}
+
+// Handle sends the pre-built record straight to the underlying Handler (if enabled for its level).
+//
+// This is low-level: the record is not modified (time, source are as set in it),
+// meant for forwarding or replaying captured records.
+func (lgr Logger) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	h := lgr.load().Handler()
+	if !h.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.Handle(ctx, r)
 }
 
 // Debug calls Debug if enabled.
@@ -156,42 +373,328 @@ func (lgr Logger) WarnContext(ctx context.Context, msg string, args ...any) {
 
 // Error calls Error with ErrorLevel, always.
 func (lgr Logger) Error(err error, msg string, args ...any) {
-	lgr.load().Error(msg, append(args, slog.String("error", err.Error()))...)
+	lgr.checkKV(callerPC(), args)
+	lgr.load().Error(msg, append(args, errorAttr(err))...)
 }
 
 // ErrorContext calls Error with ErrorLevel, always.
 func (lgr Logger) ErrorContext(ctx context.Context, err error, msg string, args ...any) {
-	lgr.load().ErrorContext(ctx, msg, append(args, slog.String("error", err.Error()))...)
+	lgr.checkKV(callerPC(), args)
+	lgr.load().ErrorContext(ctx, msg, append(args, errorAttr(err))...)
+}
+
+// Debugf logs the fmt.Sprintf-formatted message at DebugLevel, without attrs.
+//
+// The *f methods are convenience shims for migrating from printf-style loggers:
+// prefer the structured API (Debug, Info...) with attrs, which can be queried.
+func (lgr Logger) Debugf(format string, args ...any) {
+	lgr.logf(slog.LevelDebug, nil, format, args...)
+}
+
+// Infof logs the fmt.Sprintf-formatted message at InfoLevel, without attrs (see Debugf).
+func (lgr Logger) Infof(format string, args ...any) {
+	lgr.logf(slog.LevelInfo, nil, format, args...)
+}
+
+// Warnf logs the fmt.Sprintf-formatted message at WarnLevel, without attrs (see Debugf).
+func (lgr Logger) Warnf(format string, args ...any) {
+	lgr.logf(slog.LevelWarn, nil, format, args...)
+}
+
+// Errorf logs the fmt.Sprintf-formatted message at ErrorLevel, with only the error as attr (see Debugf).
+func (lgr Logger) Errorf(err error, format string, args ...any) {
+	lgr.logf(slog.LevelError, err, format, args...)
+}
+
+// logf formats the message only if the level is enabled.
+func (lgr Logger) logf(level slog.Level, err error, format string, args ...any) {
+	l := lgr.load()
+	ctx := context.Background()
+	if !l.Enabled(ctx, level) {
+		return
+	}
+	r := newRecord(level, fmt.Sprintf(format, args...))
+	if err != nil {
+		r.AddAttrs(errorAttr(err))
+	}
+	_ = l.Handler().Handle(ctx, r)
+}
+
+// Audit logs at AuditLevel, which is always enabled by LevelHandler and ConsoleHandler.
+func (lgr Logger) Audit(msg string, args ...any) {
+	lgr.log(context.Background(), AuditLevel, msg, args...)
+}
+
+// AuditContext logs at AuditLevel, which is always enabled by LevelHandler and ConsoleHandler.
+func (lgr Logger) AuditContext(ctx context.Context, msg string, args ...any) {
+	lgr.log(ctx, AuditLevel, msg, args...)
 }
 
 // V offsets the logging levels by off (emulates logr.Logger.V).
+//
+// The base level is the level of the top-level LevelHandler,
+// or, if there is none, the lowest level the handler is Enabled for
+// (for a MultiHandler, the lowest level any of its children is Enabled for).
 func (lgr Logger) V(off int) Logger {
 	if off == 0 {
 		return lgr
 	}
 	h := lgr.load().Handler()
-	level := slog.LevelInfo
+	// The V logger has its own level, so its SetLevel does not modify the parent's.
+	var lv slog.Leveler
 	if lh, ok := h.(*LevelHandler); ok {
-		level = lh.level.Level()
+		lv = newLevelVar(lh.level.Level() - slog.Level(off))
+	} else if lh, ok := h.(*lazyHandler); ok && lh.builtHandler() == nil {
+		// don't build it just for its level
+		lv = &lazyLevelVar{init: func() slog.Level { return enabledLevel(h) - slog.Level(off) }}
+	} else {
+		lv = newLevelVar(enabledLevel(h) - slog.Level(off))
 	}
 	lgr2 := newLogger()
-	lgr2.p.Store(slog.New(&LevelHandler{level: level - slog.Level(off), handler: h}))
+	lgr2.p.Store(slog.New(&LevelHandler{level: lv, handler: h, own: true}))
 	return lgr2
 }
 
+func newLevelVar(level slog.Level) *slog.LevelVar {
+	lv := new(slog.LevelVar)
+	lv.Set(level)
+	return lv
+}
+
+// minProbeLevel is the lowest level probed by enabledLevel.
+const minProbeLevel = TraceLevel - 4
+
+// enabledLevel returns the lowest level h is Enabled for,
+// probing the levels from minProbeLevel up to AuditLevel.
+func enabledLevel(h slog.Handler) slog.Level {
+	ctx := context.Background()
+	for level := minProbeLevel; level < AuditLevel; level++ {
+		if h.Enabled(ctx, level) {
+			return level
+		}
+	}
+	return AuditLevel
+}
+
 // WithValues emulates logr.Logger.WithValues with slog.WithAttrs.
 func (lgr Logger) WithValues(args ...any) Logger {
+	return lgr.withValues(callerPC(), args)
+}
+
+// WithValuesIf returns lgr.WithValues(args...) iff cond is true, lgr unchanged otherwise.
+func (lgr Logger) WithValuesIf(cond bool, args ...any) Logger {
+	if !cond {
+		return lgr
+	}
+	return lgr.withValues(callerPC(), args)
+}
+
+// withValues is WithValues, reporting the malformed args at pc (the caller of the exported method).
+func (lgr Logger) withValues(pc uintptr, args []any) Logger {
+	lgr.checkKV(pc, args)
 	lgr2 := newLogger()
 	lgr2.p.Store(lgr.load().With(args...))
 	return lgr2
 }
 
-// SetLevel on the underlying LevelHandler.
-func (lgr Logger) SetLevel(level slog.Leveler) {
-	if lh, ok := lgr.load().Handler().(*LevelHandler); ok {
-		lh.SetLevel(level)
+// ComponentKey is the key of the attr set by WithComponent.
+const ComponentKey = "component"
+
+// WithComponent adds a "component" attr (as WithValues does), without opening a group
+// (as WithName/WithGroup does).
+//
+// The attr is not hoisted: after a WithGroup/WithName it is in that group, as any other attr.
+func (lgr Logger) WithComponent(name string) Logger {
+	return lgr.WithValues(ComponentKey, name)
+}
+
+// LoggerNameKey is the key of the attr set by Named.
+var LoggerNameKey = "logger"
+
+// Named is WithComponent with the LoggerNameKey key: it tags the records with the logger's name,
+// so a shared sink (behind a MultiHandler, for example) can attribute or filter them.
+func (lgr Logger) Named(name string) Logger {
+	return lgr.WithValues(LoggerNameKey, name)
+}
+
+// Sample returns a Logger which logs only the first of each n records (1 in n),
+// for sampling a noisy log line: lgr.Sample(10).Info("tick").
+//
+// The counter belongs to the call site of Sample (shared by all the Loggers, kept for the life of the process),
+// so the inline usage above works.
+func (lgr Logger) Sample(n int) Logger {
+	if n <= 1 {
+		return lgr
+	}
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:])
+	counter, _ := callSiteCounters.LoadOrStore(pcs[0], new(atomic.Uint64))
+	lgr2 := newLogger()
+	lgr2.p.Store(slog.New(countSamplingHandler{handler: lgr.load().Handler(), counter: counter.(*atomic.Uint64), n: uint64(n)}))
+	return lgr2
+}
+
+// SampleErrors returns a Logger which deduplicates the Error records by the error's message:
+// the first occurrence is logged, then at the end of each window with suppressed records, a summary
+// (the last suppressed record), with the "count" of occurrences so far
+// and the number of "suppressed" records since the previous one.
+// A non-positive window means exponential backoff (see BackoffDedupHandler).
+//
+// The records below Error (and at AuditLevel) are not affected. The counters belong to the returned Logger
+// (and the ones derived from it), so keep it, instead of calling SampleErrors for each record;
+// Flush (or Close) writes the pending summaries.
+func (lgr Logger) SampleErrors(window time.Duration) Logger {
+	h := NewBackoffDedupHandler(lgr.load().Handler(), errorDedupKey)
+	h.Level, h.Window = slog.LevelError, window
+	lgr2 := newLogger()
+	lgr2.p.Store(slog.New(h))
+	return lgr2
+}
+
+// ErrorKey is the key of the error attr added by Error, ErrorContext, ErrorAttrs (and Fields.Err).
+//
+// The console's error coloring keys off the same name.
+var ErrorKey = "error"
+
+// errorAttr returns the ErrorKey attr of err: its value resolves to the string err.Error(),
+// so the handlers (and their ReplaceAttr) see a string, as before,
+// but the error itself is available for the ErrorDetailHandler.
+func errorAttr(err error) slog.Attr {
+	return slog.Any(ErrorKey, errorValue{err: err})
+}
+
+// errorValue is an error logged as its Error() string.
+type errorValue struct{ err error }
+
+// LogValue implements slog.LogValuer.
+func (ev errorValue) LogValue() slog.Value {
+	if ev.err == nil {
+		return slog.StringValue(nilString)
+	}
+	return slog.StringValue(ev.err.Error())
+}
+
+// WithSource returns a derived Logger with the source (AddSource) toggled.
+//
+// This works for the handlers of this package (ConsoleHandler, the JSON handler of HandlerOptions
+// with AddSource, LevelHandler, MultiHandler, RecoverHandler),
+// and is a no-op for handlers that cannot toggle it.
+func (lgr Logger) WithSource(addSource bool) Logger {
+	lgr2 := newLogger()
+	lgr2.p.Store(slog.New(withSource(lgr.load().Handler(), addSource)))
+	return lgr2
+}
+
+// OnRecord returns a new Logger which calls f with (a clone of) each emitted record.
+//
+// The callback is inserted below the LevelHandler (if any), so it gets only the enabled records,
+// and the returned Logger shares the level with lgr.
+func (lgr Logger) OnRecord(f func(slog.Record)) Logger {
+	h := lgr.load().Handler()
+	if lh, ok := h.(*LevelHandler); ok {
+		h = &LevelHandler{level: lh.level, handler: NewTapHandler(lh.handler, f)}
 	} else {
-		lgr.p.Store(slog.New(&LevelHandler{level: level, handler: lgr.load().Handler()}))
+		h = NewTapHandler(h, f)
+	}
+	lgr2 := newLogger()
+	lgr2.p.Store(slog.New(h))
+	return lgr2
+}
+
+// SetLevel on the underlying LevelHandlers, respecting the per-sink offsets:
+// the outermost LevelHandler is set to level, and every other LevelHandler in the handler tree
+// (the children of a MultiHandler, for example) is shifted by the same amount,
+// so a sink at Error below an Info logger stays 8 levels above it.
+//
+// If there is no LevelHandler, the handler is wrapped in a new one.
+func (lgr Logger) SetLevel(level slog.Leveler) {
+	lgr.setLevel(level, true)
+}
+
+// SetLevelAll sets all the LevelHandlers in the handler tree (including the children of MultiHandlers)
+// to the same level, dropping the per-sink offsets.
+//
+// If there is no LevelHandler, the handler is wrapped in a new one.
+func (lgr Logger) SetLevelAll(level slog.Leveler) {
+	lgr.setLevel(level, false)
+}
+
+func (lgr Logger) setLevel(level slog.Leveler, keepOffsets bool) {
+	h := lgr.load().Handler()
+	lhs := levelHandlers(nil, h)
+	if len(lhs) == 0 {
+		lgr.p.Store(slog.New(&LevelHandler{level: level, handler: h}))
+		return
+	}
+	if !keepOffsets {
+		for _, lh := range lhs {
+			lh.SetLevel(level)
+		}
+		return
+	}
+	// Compute all the levels first, as the handlers may share a LevelVar.
+	levels := make([]slog.Level, len(lhs))
+	diff := level.Level() - lhs[0].level.Level()
+	for i, lh := range lhs {
+		levels[i] = lh.level.Level() + diff
+	}
+	for i, lh := range lhs {
+		lh.SetLevel(levels[i])
+	}
+}
+
+// SetLevelString parses the level (see ParseLevel) and sets it on the underlying LevelHandler.
+func (lgr Logger) SetLevelString(s string) error {
+	level, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	lgr.SetLevel(level)
+	return nil
+}
+
+// ParseLevel parses the (space trimmed) level name (trace, debug, info, warn/warning, error, audit; case insensitive),
+// slog's "INFO+2"-like format or a number (the slog.Level's integer value).
+func ParseLevel(s string) (slog.Level, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "trace":
+		return TraceLevel, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "audit":
+		return AuditLevel, nil
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		return slog.Level(i), nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return level, fmt.Errorf("unknown level %q: %w", s, err)
+	}
+	return level, nil
+}
+
+// SyslogSeverity maps the level to the syslog severity (RFC5424), as used by journald, too:
+// crit (2) from Error+4, err (3), warning (4), info (6) and debug (7) below Info.
+func SyslogSeverity(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError+4:
+		return 2 // crit
+	case level >= slog.LevelError:
+		return 3 // err
+	case level >= slog.LevelWarn:
+		return 4 // warning
+	case level >= slog.LevelInfo:
+		return 6 // info
+	default:
+		return 7 // debug
 	}
 }
 
@@ -205,15 +708,49 @@ func (lgr Logger) WithGroup(s string) Logger {
 	return lgr2
 }
 
+// Group calls fn with a Logger grouped by name (see WithGroup), for scoping the logs of a block.
+// lgr is not affected.
+func (lgr Logger) Group(name string, fn func(Logger)) { fn(lgr.WithGroup(name)) }
+
 // SetOutput sets the output to a new Logger.
 func (lgr Logger) SetOutput(w io.Writer) { lgr.p.Store(New(w).load()) }
 
-// SetHandler sets the Handler.
-func (lgr Logger) SetHandler(h slog.Handler) { lgr.p.Store(slog.New(h)) }
+// SetHandler sets the Handler (discarding everything if h is nil).
+func (lgr Logger) SetHandler(h slog.Handler) {
+	if h == nil {
+		lgr.p.Store(discard())
+		return
+	}
+	lgr.p.Store(slog.New(h))
+}
+
+// Flush the underlying Handler, if it (or any Handler it wraps) supports flushing
+// (has a Flush(context.Context) error method), such as the BatchingHandler.
+func (lgr Logger) Flush(ctx context.Context) error { return flushHandler(ctx, lgr.load().Handler()) }
+
+// Close flushes (see Flush), then closes the underlying Handlers which implement Closer
+// (walking the wrapped Handlers and the children of MultiHandlers), for the idiomatic
+//
+//	defer logger.Close()
+//
+// It is a no-op (returning nil) for the in-memory handlers.
+// The Logger (and the Loggers sharing its Handlers) must not be used after Close.
+func (lgr Logger) Close() error {
+	h := lgr.load().Handler()
+	return errors.Join(flushHandler(context.Background(), h), closeHandler(h))
+}
 
 // SLog returns the underlying slog.Logger
 func (lgr Logger) SLog() *slog.Logger { return lgr.load() }
 
+// StdLogger returns a *log.Logger which logs to the underlying Handler at the given level,
+// for example for http.Server.ErrorLog.
+//
+// The source (if added) points at the call site of the *log.Logger method.
+func (lgr Logger) StdLogger(level slog.Level) *log.Logger {
+	return slog.NewLogLogger(lgr.load().Handler(), level)
+}
+
 // Logr returns a go-logr/logr.Logger, using this Logger as LogSink
 func (lgr Logger) Logr() logr.Logger { return logr.New(SLogSink{lgr.SLog()}) }
 
@@ -242,7 +779,7 @@ func (ls SLogSink) Info(level int, msg string, keysAndValues ...interface{}) {
 // Error logs an error, with the given message and key/value pairs as
 // context.  See Logger.Error for more details.
 func (ls SLogSink) Error(err error, msg string, keysAndValues ...interface{}) {
-	ls.Logger.Error(msg, append(keysAndValues, slog.Any("error", err))...)
+	ls.Logger.Error(msg, append(keysAndValues, slog.Any(ErrorKey, err))...)
 }
 
 // WithValues returns a new LogSink with additional key/value pairs.  See
@@ -266,35 +803,66 @@ func SetOutput(lgr Logger, w io.Writer) { lgr.SetOutput(w) }
 // SetHandler sets the handler on the given Logger.
 func SetHandler(lgr Logger, h slog.Handler) { lgr.SetHandler(h) }
 
-// NewLogger returns a new Logger writing to w.
+// NewLogger returns a new Logger writing to h (discarding everything if h is nil).
 func NewLogger(h slog.Handler) Logger {
 	lgr := Logger{p: &atomic.Pointer[slog.Logger]{}}
-	lgr.p.Store(slog.New(h))
+	lgr.SetHandler(h)
 	return lgr
 }
 
 // New returns a new logr.Logger writing to w as a zerolog.Logger, at LevelInfo.
+//
+// The attrs of the context (see NewContextWithAttrs) are added to the records.
+// A nil w discards everything.
+//
+// The level is decided by the outermost LevelHandler (see SetLevel and NewContextLevel),
+// the handler writing to w accepts all levels.
 func New(w io.Writer) Logger {
 	return NewLogger(NewLevelHandler(
 		&slog.LevelVar{},
-		MaybeConsoleHandler(InfoLevel, w),
+		NewContextAttrsHandler(MaybeConsoleHandler(lowestLevel, w)),
 	))
 }
 
+// lowestLevel is the lowest possible level, enabling everything.
+const lowestLevel = slog.Level(math.MinInt)
+
 var _ slog.Leveler = (*VerboseVar)(nil)
 var _ flag.Value = (*VerboseVar)(nil)
 
 type VerboseVar uint8
 
+var verboseMapping atomic.Pointer[func(uint8) slog.Level]
+
+// SetVerboseMapping sets the function that maps the VerboseVar value to an slog.Level.
+// A nil f restores the default mapping (0: Warn, 1: Info, >1: Debug).
+func SetVerboseMapping(f func(uint8) slog.Level) {
+	if f == nil {
+		verboseMapping.Store(nil)
+	} else {
+		verboseMapping.Store(&f)
+	}
+}
+
+// DefaultVerboseMapping is the default VerboseVar -> slog.Level mapping.
+func DefaultVerboseMapping(v uint8) slog.Level {
+	if v > 1 {
+		return slog.LevelDebug
+	} else if v > 0 {
+		return slog.LevelInfo
+	}
+	return slog.LevelWarn
+}
+
 func (vv *VerboseVar) Level() slog.Level {
+	var v uint8
 	if vv != nil {
-		if *vv > 1 {
-			return slog.LevelDebug
-		} else if *vv > 0 {
-			return slog.LevelInfo
-		}
+		v = uint8(*vv)
 	}
-	return slog.LevelWarn
+	if f := verboseMapping.Load(); f != nil {
+		return (*f)(v)
+	}
+	return DefaultVerboseMapping(v)
 }
 
 func (vv *VerboseVar) IsBoolFlag() bool { return true }
@@ -6,15 +6,19 @@ package zlog_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/UNO-SOFT/zlog/v2/slog"
 	"github.com/go-logr/zerologr"
 	"github.com/rs/zerolog"
-	"golang.org/x/exp/slog"
+	expslog "golang.org/x/exp/slog"
 )
 
 func TestLoggerLevel(t *testing.T) {
@@ -51,7 +55,7 @@ func TestLogrLevel(t *testing.T) {
 
 func TestSLogLevel(t *testing.T) {
 	var buf bytes.Buffer
-	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError}))
+	logger := expslog.New(expslog.NewJSONHandler(&buf, &expslog.HandlerOptions{Level: expslog.LevelError}))
 	logger.Info("info")
 	logger.Error("error", io.EOF)
 	t.Log(buf.String())
@@ -101,3 +105,157 @@ func parse(b []byte) map[string][]record {
 	}
 	return records
 }
+
+func TestStrictKV(t *testing.T) {
+	defer func(old zlog.KVCheck) { zlog.StrictKV = old }(zlog.StrictKV)
+	var buf bytes.Buffer
+	logger := zlog.New(&buf)
+
+	zlog.StrictKV = zlog.KVWarn
+	logger.Info("ok", "a", 1, "b", 2)
+	logger.Info("odd", "a", 1, "b")
+	t.Log(buf.String())
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	if len(lines) != 3 || !bytes.Contains(lines[1], []byte(`"msg":"malformed key-value list"`)) ||
+		!bytes.Contains(lines[1], []byte(`logger_test.go:`)) {
+		t.Errorf("got %q, wanted a warning before the odd record", lines)
+	}
+
+	zlog.StrictKV = zlog.KVPanic
+	defer func() {
+		if p := recover(); p == nil {
+			t.Error("no panic")
+		}
+	}()
+	_ = logger.WithValues(1, 2)
+}
+
+func TestStrictKVWithValuesIf(t *testing.T) {
+	defer func(old zlog.KVCheck) { zlog.StrictKV = old }(zlog.StrictKV)
+	var buf bytes.Buffer
+	logger := zlog.New(&buf)
+
+	zlog.StrictKV = zlog.KVWarn
+	_ = logger.WithValuesIf(true, "a")
+	t.Log(buf.String())
+	if got := buf.String(); !strings.Contains(got, `"msg":"malformed key-value list"`) ||
+		!strings.Contains(got, `logger_test.go:`) || strings.Contains(got, `/logger.go:`) {
+		t.Errorf("got %q, wanted a warning with the caller's source", got)
+	}
+}
+
+func TestPrintf(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.New(&buf)
+	logger.Debugf("hidden %d", 0)
+	logger.Infof("loaded %d items", 3)
+	logger.Errorf(io.EOF, "read %q", "x")
+	t.Log(buf.String())
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, wanted 2", len(lines))
+	}
+	for i, want := range []string{`"msg":"loaded 3 items"}`, `"msg":"read \"x\"","error":"EOF"}`} {
+		if !bytes.HasSuffix(lines[i], []byte(want)) || !bytes.Contains(lines[i], []byte(`logger_test.go:`)) {
+			t.Errorf("got %s, wanted suffix %s with the source", lines[i], want)
+		}
+	}
+}
+
+func TestNilWriter(t *testing.T) {
+	zlog.New(nil).Info("nowhere")
+	zlog.NewLogger(nil).Info("nowhere")
+	zlog.NewLogger(zlog.NewConsoleHandler(zlog.InfoLevel, nil)).Info("nowhere")
+	zlog.NewLogger(zlog.DefaultHandlerOptions.NewJSONHandler(nil)).Info("nowhere")
+}
+
+func TestNewContextLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.New(&buf)
+	logger.Debug("not logged")
+	logger.DebugContext(zlog.NewContextLevel(context.Background(), zlog.TraceLevel), "context debug")
+	logger.SetLevel(zlog.DebugLevel)
+	logger.Debug("set debug")
+	t.Log(buf.String())
+	if got := buf.String(); strings.Contains(got, "not logged") ||
+		!strings.Contains(got, `"msg":"context debug"`) || !strings.Contains(got, `"msg":"set debug"`) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestWithBuildInfo(t *testing.T) {
+	var buf bytes.Buffer
+	zlog.NewLogger(slog.NewJSONHandler(&buf, nil)).WithBuildInfo().Info("stamped")
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	// test binaries have no main module version nor VCS info
+	for _, a := range zlog.BuildInfoAttrs() {
+		if m[a.Key] != a.Value.String() {
+			t.Errorf("got %v, wanted %s", m, a)
+		}
+	}
+}
+
+func TestWithDeadline(t *testing.T) {
+	var buf lockedBuffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, nil))
+	logger.WithDeadline(context.Background(), time.Hour, "fast")()
+	done := logger.WithDeadline(context.Background(), time.Millisecond, "slow", "query", "q1")
+	time.Sleep(50 * time.Millisecond)
+	done()
+	done()
+	t.Log(string(buf.Bytes()))
+	if !check(t, parse(buf.Bytes()), map[string]int{"slow": 2}) {
+		return
+	}
+	if got := string(buf.Bytes()); !strings.Contains(got, `"level":"WARN","msg":"slow","query":"q1","deadline":1000000,"running":true}`) ||
+		!strings.Contains(got, `"level":"WARN","msg":"slow","query":"q1","deadline":1000000,"overrun":`) {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestWithDeadlineNeverDone(t *testing.T) {
+	var buf lockedBuffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, nil))
+	_ = logger.WithDeadline(context.Background(), time.Millisecond, "hang")
+	ctx, cancel := context.WithCancel(context.Background())
+	_ = logger.WithDeadline(ctx, 20*time.Millisecond, "canceled")
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	t.Log(string(buf.Bytes()))
+	check(t, parse(buf.Bytes()), map[string]int{"hang": 1, "canceled": 0})
+}
+
+func TestWithStruct(t *testing.T) {
+	type config struct {
+		Host     string `log:"host"`
+		Port     int
+		Password string `log:",redact"`
+		Internal string `log:"-"`
+		secret   string
+	}
+	var buf bytes.Buffer
+	logger := zlog.NewLogger(slog.NewJSONHandler(&buf, nil)).
+		WithStruct(&config{Host: "db", Port: 5432, Password: "pw", Internal: "x", secret: "s"})
+	logger.Info("connect")
+	t.Log(buf.String())
+	if want := `"msg":"connect","host":"db","Port":5432,"Password":"REDACTED"}`; !strings.HasSuffix(strings.TrimSpace(buf.String()), want) {
+		t.Errorf("got %s, wanted suffix %s", buf.String(), want)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	var ttls []time.Duration
+	logger := zlog.NewLogger(zlog.NewTapHandler(slog.NewJSONHandler(io.Discard, nil), func(r slog.Record) {
+		if d, ok := zlog.RecordTTL(r); ok {
+			ttls = append(ttls, d)
+		}
+	}))
+	logger.Info("verbose", zlog.TTL(time.Hour))
+	logger.Info("no hint")
+	if len(ttls) != 1 || ttls[0] != time.Hour {
+		t.Errorf("got %v, wanted [1h]", ttls)
+	}
+}
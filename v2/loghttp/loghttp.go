@@ -5,9 +5,14 @@
 package loghttp
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"log/slog"
 	"net/http"
 	"net/http/httputil"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/UNO-SOFT/zlog/v2"
 )
@@ -19,6 +24,76 @@ func WithLevel(lvl slog.Leveler) option {
 	return func(tr *LoggingTransport) { tr.LogLevel = lvl }
 }
 
+// WithDumpLevel allows setting the level of the full request/response dump.
+func WithDumpLevel(lvl slog.Leveler) option {
+	return func(tr *LoggingTransport) { tr.DumpLevel = lvl }
+}
+
+// WithTracePropagation ensures that the outgoing requests carry a W3C traceparent header
+// (generating one if missing), and logs the trace ID.
+func WithTracePropagation() option {
+	return func(tr *LoggingTransport) { tr.PropagateTrace = true }
+}
+
+// WithHeaders sets how the request/response headers are logged (DumpOnly by default).
+func WithHeaders(mode HeaderMode) option {
+	return func(tr *LoggingTransport) { tr.Headers = mode }
+}
+
+// WithRedactedHeaders sets the headers whose values are redacted in the header groups
+// (DefaultRedactedHeaders by default).
+func WithRedactedHeaders(names ...string) option {
+	return func(tr *LoggingTransport) { tr.RedactedHeaders = names }
+}
+
+// HeaderMode sets how the headers are logged.
+type HeaderMode uint8
+
+const (
+	// DumpOnly logs the headers only in the raw dump (at DumpLevel).
+	DumpOnly = HeaderMode(iota)
+	// HeadersAndDump adds the "request_headers" and "response_headers" groups (see HeaderAttr)
+	// to the compact log, and keeps the raw dump, too.
+	HeadersAndDump
+	// HeadersOnly adds the header groups to the compact log, and omits the raw dump.
+	HeadersOnly
+)
+
+// DefaultRedactedHeaders are the headers redacted in the header groups by default.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// RedactedValue replaces the values of the redacted headers.
+const RedactedValue = zlog.RedactedValue
+
+// HeaderAttr returns the headers as a group with the given key, one attr per header (in sorted order):
+// a string for single-valued, a []string for multi-valued headers.
+//
+// The values of the headers in redact (case insensitive) are replaced by RedactedValue.
+func HeaderAttr(key string, header http.Header, redact []string) slog.Attr {
+	names := make([]string, 0, len(header))
+	for k := range header {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	attrs := make([]slog.Attr, 0, len(names))
+	for _, k := range names {
+		vv := header[k]
+		if slices.ContainsFunc(redact, func(s string) bool { return strings.EqualFold(s, k) }) {
+			redacted := make([]string, len(vv))
+			for i := range redacted {
+				redacted[i] = RedactedValue
+			}
+			vv = redacted
+		}
+		if len(vv) == 1 {
+			attrs = append(attrs, slog.String(k, vv[0]))
+		} else {
+			attrs = append(attrs, slog.Any(k, vv))
+		}
+	}
+	return slog.Attr{Key: key, Value: slog.GroupValue(attrs...)}
+}
+
 // Transport returns a transport that logs requests and responses.
 func Transport(tr http.RoundTripper, opts ...option) LoggingTransport {
 	ltr := LoggingTransport{Transport: tr}
@@ -29,23 +104,100 @@ func Transport(tr http.RoundTripper, opts ...option) LoggingTransport {
 }
 
 type LoggingTransport struct {
-	LogLevel  slog.Leveler
-	Transport http.RoundTripper
+	// LogLevel is the level of the compact request/response log (Debug by default).
+	LogLevel slog.Leveler
+	// DumpLevel is the level of the full request/response dump (LogLevel - 4 by default).
+	DumpLevel      slog.Leveler
+	Transport      http.RoundTripper
+	PropagateTrace bool
+	// Headers sets how the headers are logged (DumpOnly by default).
+	Headers HeaderMode
+	// RedactedHeaders are redacted in the header groups (DefaultRedactedHeaders if nil).
+	RedactedHeaders []string
 }
 
+// TraceParentHeader is the W3C Trace Context header.
+const TraceParentHeader = "traceparent"
+
+// ensureTraceParent returns the request with a traceparent header (a clone if it had to be set),
+// and the trace ID.
+func ensureTraceParent(r *http.Request) (*http.Request, string) {
+	if tp := r.Header.Get(TraceParentHeader); tp != "" {
+		// version-traceid-parentid-flags
+		if parts := strings.Split(tp, "-"); len(parts) >= 4 && len(parts[1]) == 32 {
+			return r, parts[1]
+		}
+		return r, ""
+	}
+	var b [16 + 8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return r, ""
+	}
+	traceID, spanID := hex.EncodeToString(b[:16]), hex.EncodeToString(b[16:])
+	r = r.Clone(r.Context())
+	r.Header.Set(TraceParentHeader, "00-"+traceID+"-"+spanID+"-01")
+	return r, traceID
+}
+
+// RequestAttrs returns compact attrs of the request: method, URL and size (iff known).
+func RequestAttrs(r *http.Request) []slog.Attr {
+	if r == nil {
+		return nil
+	}
+	attrs := make([]slog.Attr, 0, 3)
+	attrs = append(attrs, slog.String("method", r.Method))
+	if r.URL != nil {
+		attrs = append(attrs, slog.String("url", r.URL.String()))
+	}
+	if r.ContentLength > 0 {
+		attrs = append(attrs, slog.Int64("request_size", r.ContentLength))
+	}
+	return attrs
+}
+
+// ResponseAttrs returns compact attrs of the response: status and size (iff known).
+func ResponseAttrs(resp *http.Response) []slog.Attr {
+	if resp == nil {
+		return nil
+	}
+	attrs := make([]slog.Attr, 0, 2)
+	attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	if resp.ContentLength >= 0 {
+		attrs = append(attrs, slog.Int64("response_size", resp.ContentLength))
+	}
+	return attrs
+}
+
+// RoundTrip logs the compact request and response attrs (see RequestAttrs and ResponseAttrs) at LogLevel,
+// and the full dump of them at DumpLevel.
+//
+// With HeadersAndDump or HeadersOnly the (redacted) headers are added to the compact log as groups,
+// with HeadersOnly the dump is omitted.
 func (s LoggingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	ctx := r.Context()
 	logger := zlog.SFromContext(ctx)
+	if s.PropagateTrace {
+		var traceID string
+		if r, traceID = ensureTraceParent(r); traceID != "" {
+			logger = logger.With("trace_id", traceID)
+		}
+	}
 	level := slog.LevelDebug
 	if s.LogLevel != nil {
 		level = s.LogLevel.Level()
 	}
+	dumpLevel := level - 4
+	if s.DumpLevel != nil {
+		dumpLevel = s.DumpLevel.Level()
+	}
 	enabled := logger.Enabled(ctx, level)
+	dumpEnabled := s.Headers != HeadersOnly && logger.Enabled(ctx, dumpLevel)
 	var reqBytes []byte
-	if enabled {
+	if dumpEnabled {
+		// DumpRequestOut restores the body
 		var err error
 		if reqBytes, err = httputil.DumpRequestOut(r, true); err != nil {
-			logger.Error("DumpRequestOut", "error", err)
+			logger.Error("DumpRequestOut", zlog.ErrorKey, err)
 		}
 	}
 
@@ -53,21 +205,44 @@ func (s LoggingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	if s.Transport != nil {
 		tr = s.Transport
 	}
+	start := time.Now()
 	resp, err := tr.RoundTrip(r)
-	// err is returned after dumping the response
-	if !enabled {
+	// err is returned after logging the response
+	if !enabled && !dumpEnabled {
 		return resp, err
 	}
+	dur := time.Since(start)
 
-	var respBytes []byte
-	if enabled && resp != nil {
-		var err error
-		if respBytes, err = httputil.DumpResponse(resp, true); err != nil {
-			logger.Error("DumpResponse", "error", err)
+	if enabled {
+		attrs := append(RequestAttrs(r), ResponseAttrs(resp)...)
+		attrs = append(attrs, slog.Duration("duration", dur))
+		if err != nil {
+			attrs = append(attrs, slog.Any(zlog.ErrorKey, err))
+		}
+		if s.Headers != DumpOnly {
+			redact := s.RedactedHeaders
+			if redact == nil {
+				redact = DefaultRedactedHeaders
+			}
+			attrs = append(attrs, HeaderAttr("request_headers", r.Header, redact))
+			if resp != nil {
+				attrs = append(attrs, HeaderAttr("response_headers", resp.Header, redact))
+			}
 		}
+		logger.LogAttrs(ctx, level, "RoundTrip", attrs...)
 	}
 
-	logger.Log(ctx, level, "RoundTrip", "request", string(reqBytes), "respnse", string(respBytes))
+	if dumpEnabled {
+		var respBytes []byte
+		if resp != nil {
+			// DumpResponse restores the body
+			var err error
+			if respBytes, err = httputil.DumpResponse(resp, true); err != nil {
+				logger.Error("DumpResponse", zlog.ErrorKey, err)
+			}
+		}
+		logger.Log(ctx, dumpLevel, "RoundTrip dump", "request", string(reqBytes), "response", string(respBytes))
+	}
 
 	return resp, err
 }
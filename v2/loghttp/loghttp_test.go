@@ -0,0 +1,148 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loghttp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/UNO-SOFT/zlog/v2/loghttp"
+)
+
+func TestHeaderAttr(t *testing.T) {
+	header := http.Header{
+		"Accept":        {"text/plain"},
+		"Authorization": {"Bearer secret"},
+		"Set-Cookie":    {"a=1", "b=2"},
+		"X-Multi":       {"x", "y"},
+	}
+	// not canonicalized
+	header["cookie"] = []string{"session=secret"}
+
+	attr := loghttp.HeaderAttr("headers", header, []string{"AUTHORIZATION", "Cookie", "set-cookie"})
+	if attr.Key != "headers" || attr.Value.Kind() != slog.KindGroup {
+		t.Fatalf("got %v, wanted a headers group", attr)
+	}
+	var names []string
+	got := make(map[string]any)
+	for _, a := range attr.Value.Group() {
+		names = append(names, a.Key)
+		got[a.Key] = a.Value.Any()
+	}
+	if want := []string{"Accept", "Authorization", "Set-Cookie", "X-Multi", "cookie"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got names %q, wanted %q", names, want)
+	}
+	want := map[string]any{
+		"Accept":        "text/plain",
+		"Authorization": loghttp.RedactedValue,
+		"Set-Cookie":    []string{loghttp.RedactedValue, loghttp.RedactedValue},
+		"X-Multi":       []string{"x", "y"},
+		"cookie":        loghttp.RedactedValue,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, wanted %#v", got, want)
+	}
+	if header.Get("Authorization") != "Bearer secret" || len(header.Values("Set-Cookie")) != 2 ||
+		header["Set-Cookie"][0] != "a=1" {
+		t.Errorf("header modified: %v", header)
+	}
+}
+
+func TestRoundTripHeadersOnly(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "server-secret"})
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug - 8}))
+	ctx := zlog.NewSContext(context.Background(), logger)
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer client-secret")
+	cl := http.Client{Transport: loghttp.Transport(http.DefaultTransport, loghttp.WithHeaders(loghttp.HeadersOnly))}
+	resp, err := cl.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	s := buf.String()
+	t.Log(s)
+	if strings.Contains(s, "secret") {
+		t.Errorf("secret logged: %s", s)
+	}
+	if strings.Contains(s, "RoundTrip dump") {
+		t.Errorf("dump logged with HeadersOnly: %s", s)
+	}
+	var rec struct {
+		Request  map[string]any `json:"request_headers"`
+		Response map[string]any `json:"response_headers"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Request["Authorization"]; got != loghttp.RedactedValue {
+		t.Errorf("got Authorization=%v, wanted %q", got, loghttp.RedactedValue)
+	}
+	if got := rec.Response["Set-Cookie"]; got != loghttp.RedactedValue {
+		t.Errorf("got Set-Cookie=%v, wanted %q", got, loghttp.RedactedValue)
+	}
+}
+
+func TestRoundTripTracePropagation(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(loghttp.TraceParentHeader))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	ctx := zlog.NewSContext(context.Background(), slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	cl := http.Client{Transport: loghttp.Transport(http.DefaultTransport, loghttp.WithTracePropagation())}
+	const existing = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	for _, tp := range []string{"", existing} {
+		req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tp != "" {
+			req.Header.Set(loghttp.TraceParentHeader, tp)
+		}
+		resp, err := cl.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if req.Header.Get(loghttp.TraceParentHeader) != tp {
+			t.Errorf("the caller's request modified: %q", req.Header)
+		}
+	}
+	t.Log(buf.String())
+	if len(got) != 2 {
+		t.Fatalf("got %d requests, wanted 2", len(got))
+	}
+	if !regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`).MatchString(got[0]) {
+		t.Errorf("got generated traceparent %q", got[0])
+	}
+	if got[1] != existing {
+		t.Errorf("got traceparent %q, wanted the existing %q", got[1], existing)
+	}
+	if !strings.Contains(buf.String(), `"trace_id":"0af7651916cd43dd8448eb211c80319c"`) {
+		t.Errorf("trace ID not logged: %s", buf.String())
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package logjournald
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// isTooLarge reports whether the error is the datagram being too large for the socket.
+func isTooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// sendLarge sends the entry in an unlinked temporary file (on /dev/shm if possible),
+// passing its descriptor to journald, as the native protocol describes for the large entries.
+func sendLarge(conn *net.UnixConn, entry []byte) error {
+	dir := "/dev/shm"
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		dir = ""
+	}
+	fh, err := os.CreateTemp(dir, "journald-*")
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	if err = os.Remove(fh.Name()); err != nil {
+		return err
+	}
+	if _, err = fh.Write(entry); err != nil {
+		return err
+	}
+	// WriteMsgUnix refuses the connected datagram sockets.
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(fh.Fd()))
+	if writeErr := rc.Write(func(fd uintptr) bool {
+		err = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return !errors.Is(err, syscall.EAGAIN)
+	}); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package logjournald
+
+import (
+	"errors"
+	"net"
+)
+
+// isTooLarge is false: journald runs only on Linux.
+func isTooLarge(err error) bool { return false }
+
+func sendLarge(conn *net.UnixConn, entry []byte) error {
+	return errors.New("sending large entries is not supported on this platform")
+}
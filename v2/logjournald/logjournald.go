@@ -0,0 +1,219 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package logjournald contains an slog.Handler writing to journald
+// with its native protocol (https://systemd.io/JOURNAL_NATIVE_PROTOCOL/).
+package logjournald
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+// DefaultSocketPath is the path of the journald socket.
+const DefaultSocketPath = "/run/systemd/journal/socket"
+
+// ErrNoJournald is returned when journald is not available.
+var ErrNoJournald = errors.New("journald is not available")
+
+// Options for the JournaldHandler.
+type Options struct {
+	// Level is the minimum level (Info by default).
+	Level slog.Leveler
+	// SocketPath is the path of the journald socket (DefaultSocketPath by default).
+	SocketPath string
+	// Identifier is the SYSLOG_IDENTIFIER (the program's name by default).
+	Identifier string
+	// AddSource adds the CODE_FILE, CODE_LINE and CODE_FUNC fields.
+	AddSource bool
+}
+
+var _ slog.Handler = (*JournaldHandler)(nil)
+
+// JournaldHandler sends each record as a journald entry,
+// the level mapped to PRIORITY, and each attr as an uppercased field.
+type JournaldHandler struct {
+	conn   *journaldConn
+	opts   Options
+	fields []byte
+	prefix string
+}
+
+type journaldConn struct {
+	conn *net.UnixConn
+	mu   sync.Mutex
+}
+
+// NewJournaldHandler returns a new JournaldHandler, connected to the journald socket.
+//
+// Returns an error wrapping ErrNoJournald if the socket is absent (not running under systemd).
+func NewJournaldHandler(opts *Options) (*JournaldHandler, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.SocketPath == "" {
+		o.SocketPath = DefaultSocketPath
+	}
+	if o.Identifier == "" {
+		o.Identifier = filepath.Base(os.Args[0])
+	}
+	if _, err := os.Stat(o.SocketPath); err != nil {
+		return nil, fmt.Errorf("journald socket %q: %w: %w", o.SocketPath, ErrNoJournald, err)
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: o.SocketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("dial %q: %w", o.SocketPath, err)
+	}
+	return &JournaldHandler{conn: &journaldConn{conn: conn}, opts: o}, nil
+}
+
+// Close the connection to journald.
+func (h *JournaldHandler) Close() error { return h.conn.conn.Close() }
+
+// Enabled implements slog.Handler.Enabled.
+func (h *JournaldHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *JournaldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	var buf bytes.Buffer
+	buf.Write(h.fields)
+	for _, a := range attrs {
+		appendAttr(&buf, h.prefix, a)
+	}
+	h2.fields = buf.Bytes()
+	return &h2
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *JournaldHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "_"
+	return &h2
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *JournaldHandler) Handle(ctx context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	appendField(&buf, "MESSAGE", r.Message)
+	appendField(&buf, "PRIORITY", strconv.Itoa(zlog.SyslogSeverity(r.Level)))
+	appendField(&buf, "SYSLOG_IDENTIFIER", h.opts.Identifier)
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		if frame.File != "" {
+			appendField(&buf, "CODE_FILE", frame.File)
+			appendField(&buf, "CODE_LINE", strconv.Itoa(frame.Line))
+			appendField(&buf, "CODE_FUNC", frame.Function)
+		}
+	}
+	buf.Write(h.fields)
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&buf, h.prefix, a)
+		return true
+	})
+
+	h.conn.mu.Lock()
+	defer h.conn.mu.Unlock()
+	_, err := h.conn.conn.Write(buf.Bytes())
+	if err != nil && isTooLarge(err) {
+		// The datagram is too large: send it in a file (see sendLarge).
+		err = sendLarge(h.conn.conn, buf.Bytes())
+	}
+	return err
+}
+
+func appendAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "_"
+		}
+		for _, g := range a.Value.Group() {
+			appendAttr(buf, prefix, g)
+		}
+		return
+	}
+	if FieldName(a.Key) == "" { // no valid key
+		return
+	}
+	appendField(buf, FieldName(prefix+a.Key), a.Value.String())
+}
+
+// MaxFieldNameLen is the maximum length of a journald field name.
+const MaxFieldNameLen = 64
+
+// FieldName converts the key to a valid journald field name:
+// uppercase, only A-Z, 0-9 and _, not starting with _ or a digit, at most MaxFieldNameLen long.
+//
+// Returns "" (the attr is dropped) if the key has no letter or digit - an empty key, for example.
+func FieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '_':
+			return r
+		case 'a' <= r && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, key)
+	name = strings.TrimLeft(name, "_")
+	if strings.Trim(name, "_") == "" {
+		return ""
+	}
+	if '0' <= name[0] && name[0] <= '9' {
+		name = "X_" + name
+	}
+	if len(name) > MaxFieldNameLen {
+		name = name[:MaxFieldNameLen]
+	}
+	return name
+}
+
+// appendField appends the field in the native protocol:
+// KEY=value\n for single-line values, KEY\n<64-bit LE length>value\n otherwise.
+func appendField(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(value)))
+	buf.Write(length[:])
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
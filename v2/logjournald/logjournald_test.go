@@ -0,0 +1,118 @@
+//go:build linux
+
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package logjournald_test
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/logjournald"
+)
+
+// fakeJournald returns a JournaldHandler connected to a fake journald socket, and the socket.
+func fakeJournald(t *testing.T) (*logjournald.JournaldHandler, *net.UnixConn) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	sock, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { sock.Close() })
+	h, err := logjournald.NewJournaldHandler(&logjournald.Options{SocketPath: path, Identifier: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h, sock
+}
+
+// receive the next entry from the fake journald socket, reading the passed file of the large entries.
+func receive(t *testing.T, sock *net.UnixConn) []byte {
+	t.Helper()
+	sock.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b, oob := make([]byte, 1<<20), make([]byte, 64)
+	n, oobn, _, _, err := sock.ReadMsgUnix(b, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oobn == 0 {
+		return b[:n]
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh := os.NewFile(uintptr(fds[0]), "entry")
+	defer fh.Close()
+	if _, err = fh.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = io.ReadAll(fh); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestJournaldHandler(t *testing.T) {
+	h, sock := fakeJournald(t)
+	logger := slog.New(h).With("request id", 1).WithGroup("g")
+	logger.Warn("first line\nsecond line", "", "no key", "___", "no name", "a", "b")
+	got := string(receive(t, sock))
+
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len("first line\nsecond line")))
+	want := "MESSAGE\n" + string(length[:]) + "first line\nsecond line\n" +
+		"PRIORITY=4\n" +
+		"SYSLOG_IDENTIFIER=test\n" +
+		"REQUEST_ID=1\n" +
+		"G_A=b\n"
+	if got != want {
+		t.Errorf("got\n%q\nwanted\n%q", got, want)
+	}
+}
+
+func TestJournaldHandlerLarge(t *testing.T) {
+	h, sock := fakeJournald(t)
+	large := strings.Repeat("x", 4<<20)
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, large, 0)); err != nil {
+		t.Fatal(err)
+	}
+	got := string(receive(t, sock))
+	if want := "MESSAGE=" + large + "\nPRIORITY=6\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("got %d bytes, wanted %d bytes prefix", len(got), len(want))
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	for _, tc := range []struct{ In, Want string }{
+		{"key", "KEY"},
+		{"request-id", "REQUEST_ID"},
+		{"_private", "PRIVATE"},
+		{"1st", "X_1ST"},
+		{"", ""},
+		{"___", ""},
+		{"árvíz", "RV_Z"},
+		{strings.Repeat("k", 100), strings.Repeat("K", logjournald.MaxFieldNameLen)},
+	} {
+		if got := logjournald.FieldName(tc.In); got != tc.Want {
+			t.Errorf("%q: got %q, wanted %q", tc.In, got, tc.Want)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+module github.com/UNO-SOFT/zlog/v2/logotel
+
+go 1.21
+
+require go.opentelemetry.io/otel v1.24.0
@@ -0,0 +1,93 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package logotel contains slog.Handlers for OpenTelemetry integration.
+//
+// It is a separate module, so the users of zlog do not depend on OpenTelemetry.
+// Thus the go test ./... of zlog does not cover it: run go vet and go test in this directory.
+package logotel
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// BaggageOptions for the BaggageHandler.
+type BaggageOptions struct {
+	// Prefix is prepended to the baggage member keys (for example "baggage.").
+	Prefix string
+	// Filter decides which baggage members are added (by their key) - all of them if nil.
+	//
+	// Use it to avoid leaking everything into the logs.
+	Filter func(key string) bool
+}
+
+var _ slog.Handler = (*BaggageHandler)(nil)
+
+// BaggageHandler adds the OpenTelemetry baggage members of the context to the records, as string attrs.
+//
+// As the attrs are added to the record, they are in the group of the handler (if any).
+type BaggageHandler struct {
+	handler slog.Handler
+	opts    BaggageOptions
+}
+
+// NewBaggageHandler returns a BaggageHandler wrapping h.
+func NewBaggageHandler(h slog.Handler, opts *BaggageOptions) *BaggageHandler {
+	bh := BaggageHandler{handler: h}
+	if opts != nil {
+		bh.opts = *opts
+	}
+	return &bh
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *BaggageHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.Handle.
+//
+// The members are added in the order of their keys; no-op if the baggage is empty.
+func (h *BaggageHandler) Handle(ctx context.Context, r slog.Record) error {
+	b := baggage.FromContext(ctx)
+	if b.Len() == 0 {
+		return h.handler.Handle(ctx, r)
+	}
+	members := b.Members()
+	sort.Slice(members, func(i, j int) bool { return members[i].Key() < members[j].Key() })
+	attrs := make([]slog.Attr, 0, len(members))
+	for _, m := range members {
+		if h.opts.Filter == nil || h.opts.Filter(m.Key()) {
+			attrs = append(attrs, slog.String(h.opts.Prefix+m.Key(), m.Value()))
+		}
+	}
+	if len(attrs) != 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *BaggageHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return &BaggageHandler{handler: h.handler.WithAttrs(attrs), opts: h.opts}
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *BaggageHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &BaggageHandler{handler: h.handler.WithGroup(name), opts: h.opts}
+}
+
+// Handler returns the underlying Handler.
+func (h *BaggageHandler) Handler() slog.Handler { return h.handler }
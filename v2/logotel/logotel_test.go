@@ -0,0 +1,65 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package logotel_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2/logotel"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+func newBaggageContext(t *testing.T, kv ...string) context.Context {
+	t.Helper()
+	members := make([]baggage.Member, 0, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		m, err := baggage.NewMember(kv[i], kv[i+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		members = append(members, m)
+	}
+	b, err := baggage.New(members...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return baggage.ContextWithBaggage(context.Background(), b)
+}
+
+func TestBaggageHandler(t *testing.T) {
+	ctx := newBaggageContext(t, "tenant", "acme", "flag", "on", "secret", "xyz")
+	var buf bytes.Buffer
+	h := logotel.NewBaggageHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}), &logotel.BaggageOptions{
+		Prefix: "baggage.",
+		Filter: func(key string) bool { return key != "secret" },
+	})
+	logger := slog.New(h)
+	logger.InfoContext(ctx, "with baggage", "a", 1)
+	logger.WithGroup("g").InfoContext(ctx, "in group")
+	logger.InfoContext(context.Background(), "no baggage")
+	t.Log(buf.String())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, want := range []string{
+		`{"level":"INFO","msg":"with baggage","a":1,"baggage.flag":"on","baggage.tenant":"acme"}`,
+		`{"level":"INFO","msg":"in group","g":{"baggage.flag":"on","baggage.tenant":"acme"}}`,
+		`{"level":"INFO","msg":"no baggage"}`,
+	} {
+		if lines[i] != want {
+			t.Errorf("%d. got %s, wanted %s", i, lines[i], want)
+		}
+	}
+}
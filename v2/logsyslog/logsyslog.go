@@ -0,0 +1,287 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package logsyslog contains an slog.Handler formatting the records
+// as RFC5424 syslog messages with structured data,
+// and a reconnecting NetWriter to ship them to a remote syslog server.
+package logsyslog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2"
+)
+
+// DefaultSDID is the SD-ID of the structured data element holding the attrs.
+//
+// 32473 is the Private Enterprise Number reserved for documentation (RFC5612).
+const DefaultSDID = "zlog@32473"
+
+// FacilityUser is the default "user-level messages" facility.
+const FacilityUser = 1
+
+// Options for the SyslogHandler.
+type Options struct {
+	// Level is the minimum level (Info by default).
+	Level slog.Leveler
+	// Hostname is the HOSTNAME field (os.Hostname by default).
+	Hostname string
+	// SDID is the SD-ID of the attrs' structured data element (DefaultSDID by default).
+	SDID string
+	// Facility is the syslog facility (FacilityUser by default).
+	Facility int
+	// AddSource adds the "source" param as file:line.
+	AddSource bool
+	// BatchInterval and BatchSize configure the batching of NewRemoteSyslogHandler:
+	// by default 1s and 128.
+	BatchInterval time.Duration
+	BatchSize     int
+}
+
+var _ slog.Handler = (*SyslogHandler)(nil)
+
+// SyslogHandler writes each record as one RFC5424 message,
+// the attrs as SD-PARAMs of one SD-ELEMENT.
+//
+// Each message is written with exactly one Write call,
+// so a NetWriter can frame them.
+type SyslogHandler struct {
+	w      *syncWriter
+	opts   Options
+	tag    string
+	params []byte
+	prefix string
+}
+
+type syncWriter struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewSyslogHandler returns a new SyslogHandler writing to w, with tag as APP-NAME.
+func NewSyslogHandler(w io.Writer, tag string, opts *Options) *SyslogHandler {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Hostname == "" {
+		o.Hostname, _ = os.Hostname()
+	}
+	if o.SDID == "" {
+		o.SDID = DefaultSDID
+	}
+	if o.Facility == 0 {
+		o.Facility = FacilityUser
+	}
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	return &SyslogHandler{w: &syncWriter{w: w}, opts: o, tag: tag}
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *SyslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *SyslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	var buf bytes.Buffer
+	buf.Write(h.params)
+	for _, a := range attrs {
+		appendParam(&buf, h.prefix, a)
+	}
+	h2.params = buf.Bytes()
+	return &h2
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *SyslogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *SyslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	buf.WriteByte('<')
+	buf.WriteString(strconv.Itoa(h.opts.Facility*8 + zlog.SyslogSeverity(r.Level)))
+	buf.WriteString(">1 ")
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	buf.WriteString(t.Format(time.RFC3339Nano))
+	buf.WriteByte(' ')
+	buf.WriteString(headerField(h.opts.Hostname, 255))
+	buf.WriteByte(' ')
+	buf.WriteString(headerField(h.tag, 48))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Itoa(os.Getpid()))
+	buf.WriteString(" - ")
+
+	var params bytes.Buffer
+	params.Write(h.params)
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		if frame.File != "" {
+			appendParam(&params, "", slog.String(slog.SourceKey, frame.File+":"+strconv.Itoa(frame.Line)))
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		appendParam(&params, h.prefix, a)
+		return true
+	})
+	if params.Len() == 0 {
+		buf.WriteByte('-')
+	} else {
+		buf.WriteByte('[')
+		buf.WriteString(h.opts.SDID)
+		buf.Write(params.Bytes())
+		buf.WriteByte(']')
+	}
+	if r.Message != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(r.Message)
+	}
+
+	h.w.mu.Lock()
+	defer h.w.mu.Unlock()
+	_, err := h.w.w.Write(buf.Bytes())
+	return err
+}
+
+// appendParam appends the attr as ` name="value"`, groups flattened with "." separated names.
+func appendParam(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, g := range a.Value.Group() {
+			appendParam(buf, prefix, g)
+		}
+		return
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(ParamName(prefix + a.Key))
+	buf.WriteString(`="`)
+	paramValueEscaper.WriteString(buf, a.Value.String())
+	buf.WriteByte('"')
+}
+
+var paramValueEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+
+// ParamName converts the key to a valid SD-NAME:
+// at most 32 printable US-ASCII characters, except '=', ' ', ']' and '"'.
+func ParamName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+	if name == "" {
+		return "_"
+	}
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// headerField returns s as a valid header field: printable US-ASCII, at most maxLen long, "-" if empty.
+func headerField(s string, maxLen int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	if len(s) > maxLen {
+		s = s[:maxLen]
+	}
+	return s
+}
+
+// RemoteSyslogHandler is a SyslogHandler writing through a batching handler to a NetWriter.
+type RemoteSyslogHandler struct {
+	slog.Handler
+	w *NetWriter
+}
+
+// NewRemoteSyslogHandler returns a handler shipping the records in RFC5424 format
+// to the syslog server at addr, reconnecting on errors,
+// and batching the records for resilience.
+//
+// The network is "udp", "tcp" or "unix" (see net.Dial).
+func NewRemoteSyslogHandler(network, addr, tag string, opts *Options) *RemoteSyslogHandler {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.BatchInterval == 0 {
+		o.BatchInterval = time.Second
+	}
+	if o.BatchSize == 0 {
+		o.BatchSize = 128
+	}
+	w := NewNetWriter(network, addr)
+	return &RemoteSyslogHandler{
+		Handler: zlog.NewBatchingHandler(NewSyslogHandler(w, tag, &o), o.BatchInterval, o.BatchSize),
+		w:       w,
+	}
+}
+
+// Flush the batched records.
+func (h *RemoteSyslogHandler) Flush(ctx context.Context) error {
+	if f, ok := h.Handler.(interface{ Flush(context.Context) error }); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// Close stops the batching (flushing the batched records) and closes the connection.
+//
+// The records handled after Close are not sent.
+func (h *RemoteSyslogHandler) Close() error {
+	var err error
+	if c, ok := h.Handler.(interface{ Close() error }); ok {
+		err = c.Close()
+	} else {
+		err = h.Flush(context.Background())
+	}
+	if closeErr := h.w.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
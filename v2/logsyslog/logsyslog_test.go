@@ -0,0 +1,139 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package logsyslog_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/logsyslog"
+)
+
+func TestSyslogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.Handler(logsyslog.NewSyslogHandler(&buf, "my app", &logsyslog.Options{Hostname: "host"}))
+	h = h.WithAttrs([]slog.Attr{slog.Int("a", 1)}).WithGroup("g")
+	r := slog.NewRecord(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), slog.LevelWarn, "message", 0)
+	r.AddAttrs(slog.String("b", `q"u]o\te`), slog.Group("h", slog.Bool("c", true)))
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	want := `<12>1 2024-01-02T03:04:05Z host my_app ` + strconv.Itoa(os.Getpid()) +
+		` - [zlog@32473 a="1" g.b="q\"u\]o\\te" g.h.c="true"] message`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwanted\n%s", got, want)
+	}
+
+	buf.Reset()
+	h = logsyslog.NewSyslogHandler(&buf, "app", &logsyslog.Options{Hostname: "host", Facility: 16})
+	r = slog.NewRecord(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), slog.LevelError+4, "", 0)
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	want = `<130>1 2024-01-02T03:04:05Z host app ` + strconv.Itoa(os.Getpid()) + ` - -`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwanted\n%s", got, want)
+	}
+}
+
+func TestParamName(t *testing.T) {
+	for _, tc := range []struct{ In, Want string }{
+		{"key", "key"},
+		{"", "_"},
+		{"a=b c", "a_b_c"},
+		{`x]"y`, "x__y"},
+		{"árvíz", "_rv_z"},
+		{strings.Repeat("k", 40), strings.Repeat("k", 32)},
+	} {
+		if got := logsyslog.ParamName(tc.In); got != tc.Want {
+			t.Errorf("%q: got %q, wanted %q", tc.In, got, tc.Want)
+		}
+	}
+}
+
+func TestNetWriterOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer conn.Close()
+		b, _ := io.ReadAll(conn)
+		received <- string(b)
+	}()
+
+	w := logsyslog.NewNetWriter("tcp", ln.Addr().String())
+	for _, msg := range []string{"hello", "árvíztűrő tükörfúrógép"} {
+		if _, err := w.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	long := "árvíztűrő tükörfúrógép"
+	if got, want := <-received, "5 hello"+strconv.Itoa(len(long))+" "+long; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+	if _, err := w.Write([]byte("after close")); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Write after Close: got %v, wanted net.ErrClosed", err)
+	}
+}
+
+func TestRemoteSyslogHandlerClose(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer conn.Close()
+		b, _ := io.ReadAll(conn)
+		received <- string(b)
+	}()
+
+	h := logsyslog.NewRemoteSyslogHandler("tcp", ln.Addr().String(), "app",
+		&logsyslog.Options{Hostname: "host", BatchInterval: time.Hour, BatchSize: 2})
+	logger := slog.New(h)
+	logger.Info("before close")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; !strings.HasSuffix(got, " host app "+strconv.Itoa(os.Getpid())+" - - before close") {
+		t.Errorf("got %q", got)
+	}
+
+	// no reconnection after Close, even when the batch is full
+	for i := 0; i < 4; i++ {
+		logger.Info("after close")
+	}
+	ln.(*net.TCPListener).SetDeadline(time.Now().Add(100 * time.Millisecond))
+	if conn, err := ln.Accept(); err == nil {
+		conn.Close()
+		t.Error("reconnected after Close")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package logsyslog
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NetWriter writes each message to a network connection,
+// dialing lazily and reconnecting once on write errors.
+//
+// On stream (tcp, unix) connections each message is framed
+// with octet counting (RFC6587), on datagram connections each message is one packet.
+type NetWriter struct {
+	conn    net.Conn
+	network string
+	addr    string
+	stream  bool
+	closed  bool
+	// Timeout is the dial and write timeout (5s by default).
+	Timeout time.Duration
+	mu      sync.Mutex
+}
+
+// NewNetWriter returns a NetWriter to addr on the network (see net.Dial).
+func NewNetWriter(network, addr string) *NetWriter {
+	return &NetWriter{
+		network: network, addr: addr,
+		stream:  strings.HasPrefix(network, "tcp") || network == "unix",
+		Timeout: 5 * time.Second,
+	}
+}
+
+// Write the message p.
+//
+// After Close, Write returns net.ErrClosed (without dialing again).
+func (w *NetWriter) Write(p []byte) (int, error) {
+	msg := p
+	if w.stream {
+		msg = append(append(make([]byte, 0, len(p)+8), strconv.Itoa(len(p))+" "...), p...)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, net.ErrClosed
+	}
+	var err error
+	for i := 0; i < 2; i++ {
+		if w.conn == nil {
+			if w.conn, err = net.DialTimeout(w.network, w.addr, w.Timeout); err != nil {
+				w.conn = nil
+				return 0, err
+			}
+		}
+		if w.Timeout > 0 {
+			_ = w.conn.SetWriteDeadline(time.Now().Add(w.Timeout))
+		}
+		if _, err = w.conn.Write(msg); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+// Close the connection - the NetWriter cannot be used after it.
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
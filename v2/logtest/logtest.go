@@ -0,0 +1,218 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package logtest contains an in-memory slog.Handler and assertions on the records it captured,
+// for testing the logging behavior without parsing the output:
+//
+//	h := logtest.NewMemoryHandler(nil)
+//	doSomething(zlog.NewLogger(h))
+//	logtest.Logged(t, h, slog.LevelError, "connection failed", slog.String("host", "db"))
+package logtest
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Record is a captured record, with all its attrs (including the ones of WithAttrs),
+// the groups flattened into "." separated keys.
+type Record struct {
+	Time    time.Time
+	Message string
+	Attrs   []slog.Attr
+	Level   slog.Level
+}
+
+// Attr returns the value of the attr with the given ("." separated) key.
+func (r Record) Attr(key string) (slog.Value, bool) {
+	for _, a := range r.Attrs {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+var _ slog.Handler = (*MemoryHandler)(nil)
+
+// MemoryHandler captures the records in memory.
+//
+// The handlers derived by WithAttrs and WithGroup share the captured records.
+type MemoryHandler struct {
+	store  *store
+	level  slog.Leveler
+	prefix string
+	attrs  []slog.Attr
+}
+
+type store struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewMemoryHandler returns a new MemoryHandler capturing the records at or above level (all of them if nil).
+func NewMemoryHandler(level slog.Leveler) *MemoryHandler {
+	return &MemoryHandler{store: &store{}, level: level}
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *MemoryHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.level == nil || level >= h.level.Level()
+}
+
+// Handle implements slog.Handler.Handle.
+func (h *MemoryHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := append(make([]slog.Attr, 0, len(h.attrs)+r.NumAttrs()), h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = appendFlat(attrs, h.prefix, a)
+		return true
+	})
+	h.store.mu.Lock()
+	h.store.records = append(h.store.records, Record{Time: r.Time, Level: r.Level, Message: r.Message, Attrs: attrs})
+	h.store.mu.Unlock()
+	return nil
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *MemoryHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = append(make([]slog.Attr, 0, len(h.attrs)+len(attrs)), h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = appendFlat(h2.attrs, h.prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *MemoryHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// Records returns (a copy of) the captured records.
+func (h *MemoryHandler) Records() []Record {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	return append([]Record(nil), h.store.records...)
+}
+
+// Reset drops the captured records.
+func (h *MemoryHandler) Reset() {
+	h.store.mu.Lock()
+	h.store.records = nil
+	h.store.mu.Unlock()
+}
+
+// appendFlat appends the resolved attr, groups flattened with "." separated keys.
+func appendFlat(dst []slog.Attr, prefix string, a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, g := range a.Value.Group() {
+			dst = appendFlat(dst, prefix, g)
+		}
+		return dst
+	}
+	if a.Key == "" {
+		return dst
+	}
+	a.Key = prefix + a.Key
+	return append(dst, a)
+}
+
+// CaptureForTest installs a new MemoryHandler (capturing all levels) as the handler of slog.Default
+// (which also receives the output of the standard log package),
+// and restores the previous default logger (and the log package's output and flags) with t.Cleanup.
+//
+// As it mutates global state, the tests using it must not be run in parallel (t.Parallel).
+func CaptureForTest(t testing.TB) *MemoryHandler {
+	t.Helper()
+	old, oldOutput, oldFlags := slog.Default(), log.Writer(), log.Flags()
+	h := NewMemoryHandler(nil)
+	slog.SetDefault(slog.New(h))
+	t.Cleanup(func() {
+		slog.SetDefault(old)
+		log.SetOutput(oldOutput)
+		log.SetFlags(oldFlags)
+	})
+	return h
+}
+
+// Find returns the first captured record with the given level,
+// whose message contains msg, and which has all the attrs (with "." separated keys for the groups).
+//
+// The attr values are compared by slog.Value.Equal, or by their string form if that fails.
+func Find(h *MemoryHandler, level slog.Level, msg string, attrs ...slog.Attr) (Record, bool) {
+	for _, r := range h.Records() {
+		if r.Level == level && strings.Contains(r.Message, msg) && hasAttrs(r, attrs) {
+			return r, true
+		}
+	}
+	return Record{}, false
+}
+
+func hasAttrs(r Record, attrs []slog.Attr) bool {
+	for _, want := range attrs {
+		got, ok := r.Attr(want.Key)
+		if !ok {
+			return false
+		}
+		wantV := want.Value.Resolve()
+		if !got.Equal(wantV) && got.String() != wantV.String() {
+			return false
+		}
+	}
+	return true
+}
+
+// Logged asserts that a matching record has been captured (see Find),
+// reporting the captured records with t.Errorf if not.
+func Logged(t testing.TB, h *MemoryHandler, level slog.Level, msg string, attrs ...slog.Attr) bool {
+	t.Helper()
+	if _, ok := Find(h, level, msg, attrs...); ok {
+		return true
+	}
+	t.Errorf("no %s record with message %q and attrs %v; got:\n%s", level, msg, attrs, formatRecords(h.Records()))
+	return false
+}
+
+// NotLogged asserts that no matching record has been captured (see Find).
+func NotLogged(t testing.TB, h *MemoryHandler, level slog.Level, msg string, attrs ...slog.Attr) bool {
+	t.Helper()
+	r, ok := Find(h, level, msg, attrs...)
+	if !ok {
+		return true
+	}
+	t.Errorf("unexpected record: %s", formatRecords([]Record{r}))
+	return false
+}
+
+func formatRecords(records []Record) string {
+	var buf strings.Builder
+	for _, r := range records {
+		buf.WriteString(r.Level.String())
+		buf.WriteByte(' ')
+		buf.WriteString(r.Message)
+		for _, a := range r.Attrs {
+			buf.WriteByte(' ')
+			buf.WriteString(a.String())
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package logtest_test
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2/logtest"
+)
+
+// fakeTB records the errors and the cleanup functions.
+type fakeTB struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (t *fakeTB) Helper() {}
+func (t *fakeTB) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+func (t *fakeTB) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+
+func TestFlatten(t *testing.T) {
+	h := logtest.NewMemoryHandler(nil)
+	slog.New(h).With("a", 1).WithGroup("g").With("b", 2).WithGroup("h").
+		Info("msg", "c", 3, slog.Group("i", "d", 4), slog.Group("", "e", 5))
+	records := h.Records()
+	if len(records) != 1 {
+		t.Fatalf("got %d records, wanted 1", len(records))
+	}
+	var keys []string
+	for _, a := range records[0].Attrs {
+		keys = append(keys, a.Key+"="+a.Value.String())
+	}
+	if got, want := strings.Join(keys, " "), "a=1 g.b=2 g.h.c=3 g.h.i.d=4 g.h.e=5"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+	if v, ok := records[0].Attr("g.h.i.d"); !ok || v.Int64() != 4 {
+		t.Errorf("Attr: got %v, %t", v, ok)
+	}
+}
+
+func TestFind(t *testing.T) {
+	h := logtest.NewMemoryHandler(slog.LevelInfo)
+	logger := slog.New(h).WithGroup("req")
+	logger.Debug("filtered")
+	logger.Error("connection failed", "host", "db", "port", 5432)
+	logger.Info("connected", "host", "cache")
+
+	if _, ok := logtest.Find(h, slog.LevelDebug, "filtered"); ok {
+		t.Error("found a record below the level")
+	}
+	if r, ok := logtest.Find(h, slog.LevelError, "failed", slog.String("req.host", "db")); !ok || r.Message != "connection failed" {
+		t.Errorf("got %v, %t", r, ok)
+	}
+	// compared by the string form
+	if _, ok := logtest.Find(h, slog.LevelError, "", slog.String("req.port", "5432")); !ok {
+		t.Error("the string form is not compared")
+	}
+	for _, tc := range []struct {
+		Level slog.Level
+		Msg   string
+		Attrs []slog.Attr
+	}{
+		{slog.LevelInfo, "connection failed", nil},
+		{slog.LevelError, "connected", nil},
+		{slog.LevelError, "failed", []slog.Attr{slog.String("req.host", "cache")}},
+		{slog.LevelError, "failed", []slog.Attr{slog.String("host", "db")}},
+	} {
+		if r, ok := logtest.Find(h, tc.Level, tc.Msg, tc.Attrs...); ok {
+			t.Errorf("%v %q %v: found %v", tc.Level, tc.Msg, tc.Attrs, r)
+		}
+	}
+
+	h.Reset()
+	if len(h.Records()) != 0 {
+		t.Error("Reset does not drop the records")
+	}
+}
+
+func TestLoggedNotLogged(t *testing.T) {
+	h := logtest.NewMemoryHandler(nil)
+	slog.New(h).Warn("disk full", "free", 0)
+
+	var ft fakeTB
+	if !logtest.Logged(&ft, h, slog.LevelWarn, "disk", slog.Int("free", 0)) || len(ft.errors) != 0 {
+		t.Errorf("Logged failed: %q", ft.errors)
+	}
+	if !logtest.NotLogged(&ft, h, slog.LevelError, "disk") || len(ft.errors) != 0 {
+		t.Errorf("NotLogged failed: %q", ft.errors)
+	}
+
+	if logtest.Logged(&ft, h, slog.LevelWarn, "disk", slog.Int("free", 1)) || len(ft.errors) != 1 {
+		t.Fatalf("Logged succeeded: %q", ft.errors)
+	}
+	if !strings.Contains(ft.errors[0], "WARN disk full free=0") {
+		t.Errorf("the captured records are not reported: %q", ft.errors[0])
+	}
+	if logtest.NotLogged(&ft, h, slog.LevelWarn, "full") || len(ft.errors) != 2 {
+		t.Errorf("NotLogged succeeded: %q", ft.errors)
+	}
+}
+
+func TestCaptureForTest(t *testing.T) {
+	oldDefault, oldOutput, oldFlags := slog.Default(), log.Writer(), log.Flags()
+	var ft fakeTB
+	h := logtest.CaptureForTest(&ft)
+	slog.Info("via slog", "a", 1)
+	log.Print("via log")
+	logtest.Logged(t, h, slog.LevelInfo, "via slog", slog.Int("a", 1))
+	logtest.Logged(t, h, slog.LevelInfo, "via log")
+
+	for i := len(ft.cleanups) - 1; i >= 0; i-- {
+		ft.cleanups[i]()
+	}
+	if slog.Default() != oldDefault {
+		t.Error("the default logger is not restored")
+	}
+	if log.Writer() != oldOutput || log.Flags() != oldFlags {
+		t.Error("the log package's output is not restored")
+	}
+	h.Reset()
+	slog.Info("after")
+	if len(h.Records()) != 0 {
+		t.Error("still capturing after the cleanup")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package logws contains an slog.Handler which mirrors the records
+// to the connected WebSocket clients, for live tailing the logs (in an admin UI, for example).
+package logws
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/coder/websocket"
+)
+
+// DefaultClientBuffer is the number of records buffered per client.
+const DefaultClientBuffer = 256
+
+// writeTimeout is the timeout of writing one message to a client.
+const writeTimeout = 10 * time.Second
+
+var (
+	_ slog.Handler = WSHub{}
+	_ http.Handler = WSHub{}
+)
+
+// WSHub is an slog.Handler broadcasting the JSON-serialized records
+// to the WebSocket clients connected to its ServeHTTP endpoint.
+//
+// Each client has its own bounded buffer (DefaultClientBuffer records);
+// a client which cannot keep up is disconnected instead of blocking the logging.
+//
+// The handlers derived by WithAttrs and WithGroup share the clients.
+type WSHub struct {
+	slog.Handler
+	hub *hub
+}
+
+type hub struct {
+	level   slog.LevelVar
+	clients map[*client]struct{}
+	mu      sync.Mutex
+}
+
+type client struct {
+	msgs chan []byte
+}
+
+// NewWSHub returns a new WSHub, with Info level.
+func NewWSHub() WSHub {
+	h := hub{clients: make(map[*client]struct{})}
+	opts := zlog.DefaultHandlerOptions
+	opts.Level = &h.level
+	return WSHub{Handler: opts.NewJSONHandler(&h), hub: &h}
+}
+
+// SetLevel sets the minimum level of the records sent to the clients.
+func (h WSHub) SetLevel(level slog.Level) { h.hub.level.Set(level) }
+
+// Enabled implements slog.Handler.Enabled - false when there are no clients connected.
+func (h WSHub) Enabled(ctx context.Context, level slog.Level) bool {
+	h.hub.mu.Lock()
+	n := len(h.hub.clients)
+	h.hub.mu.Unlock()
+	return n != 0 && h.Handler.Enabled(ctx, level)
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h WSHub) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return WSHub{Handler: h.Handler.WithAttrs(attrs), hub: h.hub}
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h WSHub) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return WSHub{Handler: h.Handler.WithGroup(name), hub: h.hub}
+}
+
+// ServeHTTP implements http.Handler, accepting the WebSocket connection
+// and sending each record as a text message, till the client disconnects.
+func (h WSHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+	ctx := conn.CloseRead(r.Context())
+
+	c := &client{msgs: make(chan []byte, DefaultClientBuffer)}
+	h.hub.mu.Lock()
+	h.hub.clients[c] = struct{}{}
+	h.hub.mu.Unlock()
+	defer func() {
+		h.hub.mu.Lock()
+		delete(h.hub.clients, c)
+		h.hub.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-c.msgs:
+			if !ok {
+				conn.Close(websocket.StatusPolicyViolation, "too slow")
+				return
+			}
+			wCtx, cancel := context.WithTimeout(ctx, writeTimeout)
+			err := conn.Write(wCtx, websocket.MessageText, msg)
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Write implements io.Writer for the JSON handler: sends the record to each client,
+// dropping (and disconnecting) the clients whose buffer is full.
+func (h *hub) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.clients) == 0 {
+		return len(p), nil
+	}
+	msg := append([]byte(nil), bytes.TrimSuffix(p, []byte{'\n'})...)
+	for c := range h.clients {
+		select {
+		case c.msgs <- msg:
+		default:
+			delete(h.clients, c)
+			close(c.msgs)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package logws_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/logws"
+	"github.com/coder/websocket"
+)
+
+// waitEnabled waits till h.Enabled reports want (the clients are (un)registered asynchronously).
+func waitEnabled(t *testing.T, h logws.WSHub, want bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for h.Enabled(context.Background(), slog.LevelInfo) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Enabled is not %t", want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func dial(t *testing.T, ctx context.Context, url string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(url, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.CloseNow() })
+	return conn
+}
+
+func TestWSHubBroadcast(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	h := logws.NewWSHub()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	if h.Enabled(ctx, slog.LevelInfo) {
+		t.Error("enabled without clients")
+	}
+	msgs := make([]chan string, 2)
+	for i := range msgs {
+		conn := dial(t, ctx, srv.URL)
+		ch := make(chan string, logws.DefaultClientBuffer)
+		msgs[i] = ch
+		go func() {
+			defer close(ch)
+			for {
+				typ, b, err := conn.Read(ctx)
+				if err != nil {
+					return
+				}
+				if typ == websocket.MessageText {
+					ch <- string(b)
+				}
+			}
+		}()
+	}
+	logger := slog.New(h)
+	// the clients are registered asynchronously: ping till all of them receive it
+	for i, ch := range msgs {
+		for received := false; !received; {
+			logger.Info("ping")
+			select {
+			case _, ok := <-ch:
+				if !ok {
+					t.Fatalf("%d. disconnected", i)
+				}
+				received = true
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	logger.With("a", 1).Info("broadcast")
+	for i, ch := range msgs {
+		var got string
+		for msg := range ch {
+			if !strings.Contains(msg, `"msg":"ping"`) {
+				got = msg
+				break
+			}
+		}
+		if !strings.Contains(got, `"msg":"broadcast","a":1`) {
+			t.Errorf("%d. got %q", i, got)
+		}
+	}
+}
+
+func TestWSHubSlowClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	h := logws.NewWSHub()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	dial(t, ctx, srv.URL) // never reads
+	waitEnabled(t, h, true)
+	logger := slog.New(h)
+	payload := strings.Repeat("x", 64<<10)
+	for i := 0; i < 10*logws.DefaultClientBuffer && h.Enabled(ctx, slog.LevelInfo); i++ {
+		logger.Info("flood", "payload", payload)
+	}
+	if h.Enabled(ctx, slog.LevelInfo) {
+		t.Error("the slow client is not dropped")
+	}
+}
+
+func TestWSHubDisconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	h := logws.NewWSHub()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	conn := dial(t, ctx, srv.URL)
+	waitEnabled(t, h, true)
+	if err := conn.Close(websocket.StatusNormalClosure, ""); err != nil {
+		t.Fatal(err)
+	}
+	waitEnabled(t, h, false)
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package logzerolog contains an slog.Handler writing the records
+// into an existing zerolog.Logger, as a sink - for incremental migration
+// between zlog and zerolog.
+package logzerolog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/rs/zerolog"
+)
+
+var _ slog.Handler = (*ZerologHandler)(nil)
+
+// ZerologHandler writes each record as a zerolog event of the mapped level (see Level),
+// the attrs as fields, groups flattened with "." separated keys.
+//
+// The timestamp, the caller and the hooks are the zerolog.Logger's business.
+type ZerologHandler struct {
+	zl     zerolog.Logger
+	prefix string
+}
+
+// NewZerologHandler returns a new ZerologHandler writing to zl.
+//
+// The enabled levels are decided by the level of zl and zerolog's global level.
+func NewZerologHandler(zl zerolog.Logger) *ZerologHandler {
+	return &ZerologHandler{zl: zl}
+}
+
+// Level maps the slog.Level to a zerolog.Level:
+// TraceLevel (and below) to Trace, Debug, Info, Warn and Error to the same,
+// AuditLevel (and above) to NoLevel, to be always logged.
+func Level(level slog.Level) zerolog.Level {
+	switch {
+	case level >= zlog.AuditLevel:
+		return zerolog.NoLevel
+	case level >= slog.LevelError:
+		return zerolog.ErrorLevel
+	case level >= slog.LevelWarn:
+		return zerolog.WarnLevel
+	case level >= slog.LevelInfo:
+		return zerolog.InfoLevel
+	case level >= slog.LevelDebug:
+		return zerolog.DebugLevel
+	default:
+		return zerolog.TraceLevel
+	}
+}
+
+// Enabled implements slog.Handler.Enabled.
+func (h *ZerologHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	lvl := Level(level)
+	return lvl >= h.zl.GetLevel() && lvl >= zerolog.GlobalLevel()
+}
+
+// WithAttrs implements slog.Handler.WithAttrs.
+func (h *ZerologHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	var fields []any
+	for _, a := range attrs {
+		fields = appendField(fields, h.prefix, a)
+	}
+	h2 := *h
+	h2.zl = h.zl.With().Fields(fields).Logger()
+	return &h2
+}
+
+// WithGroup implements slog.Handler.WithGroup.
+func (h *ZerologHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// Handle implements slog.Handler.Handle.
+//
+// An AuditLevel record gets an "audit" level field, as NoLevel events have none.
+func (h *ZerologHandler) Handle(ctx context.Context, r slog.Record) error {
+	lvl := Level(r.Level)
+	e := h.zl.WithLevel(lvl)
+	if e == nil {
+		return nil
+	}
+	if lvl == zerolog.NoLevel {
+		e = e.Str(zerolog.LevelFieldName, "audit")
+	}
+	if r.NumAttrs() != 0 {
+		fields := make([]any, 0, 2*r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool {
+			fields = appendField(fields, h.prefix, a)
+			return true
+		})
+		e = e.Fields(fields)
+	}
+	e.Msg(r.Message)
+	return nil
+}
+
+// appendField appends the key, value pair of the attr, groups flattened with "." separated keys.
+func appendField(dst []any, prefix string, a slog.Attr) []any {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return dst
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, g := range a.Value.Group() {
+			dst = appendField(dst, prefix, g)
+		}
+		return dst
+	}
+	return append(dst, prefix+a.Key, a.Value.Any())
+}
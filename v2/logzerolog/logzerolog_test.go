@@ -0,0 +1,76 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package logzerolog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/UNO-SOFT/zlog/v2/logzerolog"
+	"github.com/rs/zerolog"
+)
+
+func TestLevel(t *testing.T) {
+	for _, tc := range []struct {
+		Level slog.Level
+		Want  zerolog.Level
+	}{
+		{zlog.TraceLevel - 1, zerolog.TraceLevel},
+		{zlog.TraceLevel, zerolog.TraceLevel},
+		{slog.LevelDebug, zerolog.DebugLevel},
+		{slog.LevelInfo, zerolog.InfoLevel},
+		{slog.LevelInfo + 1, zerolog.InfoLevel},
+		{slog.LevelWarn, zerolog.WarnLevel},
+		{slog.LevelError, zerolog.ErrorLevel},
+		{zlog.AuditLevel, zerolog.NoLevel},
+	} {
+		if got := logzerolog.Level(tc.Level); got != tc.Want {
+			t.Errorf("%v: got %v, wanted %v", tc.Level, got, tc.Want)
+		}
+	}
+}
+
+func TestZerologHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := logzerolog.NewZerologHandler(zerolog.New(&buf).Level(zerolog.TraceLevel))
+	logger := slog.New(h).With("a", 1).WithGroup("g").With("b", 2)
+	logger.Log(context.Background(), zlog.TraceLevel, "trace", "c", 3, slog.Group("h", "d", "x"))
+	logger.Log(context.Background(), zlog.AuditLevel, "audit")
+	t.Log(buf.String())
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, want := range []string{
+		`{"level":"trace","a":1,"g.b":2,"g.c":3,"g.h.d":"x","message":"trace"}`,
+		`{"a":1,"g.b":2,"level":"audit","message":"audit"}`,
+	} {
+		if lines[i] != want {
+			t.Errorf("%d. got %s, wanted %s", i, lines[i], want)
+		}
+	}
+}
+
+func TestZerologHandlerEnabled(t *testing.T) {
+	global := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(global) })
+	h := logzerolog.NewZerologHandler(zerolog.New(nil).Level(zerolog.DebugLevel))
+	ctx := context.Background()
+	if h.Enabled(ctx, zlog.TraceLevel) || !h.Enabled(ctx, slog.LevelDebug) {
+		t.Error("the zerolog.Logger's level is not honored")
+	}
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	if h.Enabled(ctx, slog.LevelInfo) || !h.Enabled(ctx, slog.LevelWarn) {
+		t.Error("zerolog's global level is not honored")
+	}
+	if !h.Enabled(ctx, zlog.AuditLevel) {
+		t.Error("AuditLevel is not enabled")
+	}
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	if h.Enabled(ctx, zlog.AuditLevel) {
+		t.Error("enabled with zerolog disabled")
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// FlushOnSignal installs a signal handler which flushes the Logger (see Logger.Flush)
+// when any of sigs (SIGINT and SIGTERM by default) arrives.
+//
+// After the flush the handler deregisters itself and re-raises the signal,
+// so the default behavior (exit) is preserved.
+// The application's own signal.Notify channels get the signal twice (the original and the re-raised one):
+// use FlushOnSignalNoRaise then.
+//
+// The returned stop function deregisters the handler.
+func FlushOnSignal(lgr Logger, sigs ...os.Signal) (stop func()) {
+	return flushOnSignal(lgr, true, sigs)
+}
+
+// FlushOnSignalNoRaise is like FlushOnSignal, but does not re-raise the signal:
+// for applications handling sigs with their own signal.Notify.
+func FlushOnSignalNoRaise(lgr Logger, sigs ...os.Signal) (stop func()) {
+	return flushOnSignal(lgr, false, sigs)
+}
+
+func flushOnSignal(lgr Logger, raise bool, sigs []os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+	go func() {
+		select {
+		case <-done:
+			return
+		case sig := <-ch:
+			_ = lgr.Flush(context.Background())
+			stop()
+			if !raise {
+				return
+			}
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				_ = p.Signal(sig)
+			}
+		}
+	}()
+	return stop
+}
@@ -0,0 +1,74 @@
+//go:build !windows
+
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog_test
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2"
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+type flushCounter struct {
+	slog.Handler
+	n atomic.Int32
+}
+
+func (h *flushCounter) Flush(context.Context) error { h.n.Add(1); return nil }
+
+func TestFlushOnSignal(t *testing.T) {
+	for _, raise := range []bool{false, true} {
+		raise := raise
+		name := "noraise"
+		if raise {
+			name = "raise"
+		}
+		t.Run(name, func(t *testing.T) {
+			// the application's own handler, which also keeps SIGUSR1 from killing the test
+			own := make(chan os.Signal, 2)
+			signal.Notify(own, syscall.SIGUSR1)
+			defer signal.Stop(own)
+
+			h := &flushCounter{Handler: zlog.DefaultHandlerOptions.NewJSONHandler(nil)}
+			flushOnSignal := zlog.FlushOnSignalNoRaise
+			want := 1
+			if raise {
+				flushOnSignal, want = zlog.FlushOnSignal, 2
+			}
+			stop := flushOnSignal(zlog.NewLogger(h), syscall.SIGUSR1)
+			defer stop()
+
+			if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+				t.Fatal(err)
+			}
+			var got int
+			timer := time.NewTimer(200 * time.Millisecond)
+			defer timer.Stop()
+		Loop:
+			for {
+				select {
+				case <-own:
+					got++
+				case <-timer.C:
+					break Loop
+				}
+			}
+			if n := h.n.Load(); n != 1 {
+				t.Errorf("flushed %d times, wanted 1", n)
+			}
+			if got != want {
+				t.Errorf("got the signal %d times, wanted %d", got, want)
+			}
+		})
+	}
+}
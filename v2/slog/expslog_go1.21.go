@@ -37,7 +37,16 @@ const (
 	LevelWarn  = slog.LevelWarn
 	LevelError = slog.LevelError
 
-	KindAny = slog.KindAny
+	KindAny       = slog.KindAny
+	KindBool      = slog.KindBool
+	KindDuration  = slog.KindDuration
+	KindFloat64   = slog.KindFloat64
+	KindInt64     = slog.KindInt64
+	KindString    = slog.KindString
+	KindTime      = slog.KindTime
+	KindUint64    = slog.KindUint64
+	KindGroup     = slog.KindGroup
+	KindLogValuer = slog.KindLogValuer
 )
 
 func Default() *slog.Logger           { return slog.Default() }
@@ -9,7 +9,6 @@ package zlog_test
 import (
 	"bytes"
 	"encoding/json"
-	"strings"
 	"testing"
 	"testing/slogtest"
 
@@ -39,11 +38,7 @@ func TestSLogTest(t *testing.T) {
 	for _, h := range []slog.Handler{h, zlog.NewBatchingHandler(h, 0, 0)} {
 		buf.Reset()
 		if err := slogtest.TestHandler(h, results); err != nil {
-			if strings.Contains(err.Error(), "a Handler should not output groups ") {
-				t.Log(err)
-			} else {
-				t.Error(err)
-			}
+			t.Error(err)
 		}
 	}
 }
@@ -0,0 +1,90 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+// processStart is (approximately) the start time of the process.
+var processStart = time.Now()
+
+// LogStartup emits one Info "process started" record with the process metadata:
+// hostname, pid, args, start time, Go version and the main module's version,
+// plus the extra attrs.
+func (lgr Logger) LogStartup(extra ...slog.Attr) {
+	l := lgr.load()
+	ctx := context.Background()
+	if !l.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+	hostname, _ := os.Hostname()
+	attrs := make([]slog.Attr, 0, 6+len(extra))
+	attrs = append(attrs,
+		slog.String("hostname", hostname),
+		slog.Int("pid", os.Getpid()),
+		slog.Any("args", os.Args),
+		slog.Time("start", processStart),
+		slog.String("go_version", runtime.Version()),
+	)
+	attrs = append(attrs, BuildInfoAttrs()...)
+	var pcs [1]uintptr
+	// skip [runtime.Callers, this function]
+	runtime.Callers(2, pcs[:])
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "process started", pcs[0])
+	r.AddAttrs(append(attrs, extra...)...)
+	_ = l.Handler().Handle(ctx, r)
+}
+
+// BuildInfoAttrs returns the main module's version ("version")
+// and VCS revision ("vcs_revision", with "+dirty" suffix for modified sources) from debug.ReadBuildInfo.
+//
+// Unavailable (or "(devel)") values are omitted. The lookup is done once.
+func BuildInfoAttrs() []slog.Attr { return buildInfoAttrs() }
+
+var buildInfoAttrs = sync.OnceValue(func() []slog.Attr {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	var attrs []slog.Attr
+	if v := bi.Main.Version; v != "" && v != "(devel)" {
+		attrs = append(attrs, slog.String("version", v))
+	}
+	var revision, modified string
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if revision != "" {
+		if modified == "true" {
+			revision += "+dirty"
+		}
+		attrs = append(attrs, slog.String("vcs_revision", revision))
+	}
+	return attrs
+})
+
+// WithBuildInfo returns a Logger stamping each record with the BuildInfoAttrs.
+func (lgr Logger) WithBuildInfo() Logger {
+	attrs := BuildInfoAttrs()
+	if len(attrs) == 0 {
+		return lgr
+	}
+	lgr2 := newLogger()
+	lgr2.p.Store(slog.New(lgr.load().Handler().WithAttrs(attrs)))
+	return lgr2
+}
@@ -0,0 +1,52 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+// UpdateCurrentSymlink points the link (such as "app.log") to target (the current log file),
+// atomically replacing the existing link, as the logrotate-aware tailers expect.
+//
+// The link is relative if target is in the directory of link.
+// It is a no-op on Windows, where symlinks need special privileges.
+func UpdateCurrentSymlink(target, link string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if rel, err := filepath.Rel(filepath.Dir(link), target); err == nil && filepath.Dir(rel) == "." {
+		target = rel
+	}
+	tmp := link + ".tmp" + strconv.Itoa(os.Getpid())
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// WithCurrentSymlink wraps the file opener of a rotating writer (or PerLevelFileOptions.OpenFile),
+// to point link to each newly opened file (see UpdateCurrentSymlink).
+//
+// Updating the link is best-effort: its failure does not fail the open.
+func WithCurrentSymlink(link string, open func(path string) (io.WriteCloser, error)) func(path string) (io.WriteCloser, error) {
+	return func(path string) (io.WriteCloser, error) {
+		w, err := open(path)
+		if err != nil {
+			return w, err
+		}
+		_ = UpdateCurrentSymlink(path, link)
+		return w, nil
+	}
+}
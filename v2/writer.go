@@ -0,0 +1,77 @@
+// Copyright 2024 Tamás Gulácsi. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package zlog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/UNO-SOFT/zlog/v2/slog"
+)
+
+// WriterPrefixKey is the key of the prefix attr of the records logged by Logger.Writer.
+const WriterPrefixKey = "prefix"
+
+// Writer returns an io.WriteCloser which logs each complete line written to it
+// as a record at the given level, with the prefix as a WriterPrefixKey attr (if not empty).
+//
+// Partial lines are buffered till the newline (or Close), so it is suitable as a subprocess' output
+// (cmd.Stdout = lgr.Writer(zlog.InfoLevel, "child")).
+func (lgr Logger) Writer(level slog.Level, prefix string) io.WriteCloser {
+	return &lineWriter{lgr: lgr, level: level, prefix: prefix}
+}
+
+type lineWriter struct {
+	lgr    Logger
+	prefix string
+	buf    []byte
+	level  slog.Level
+	mu     sync.Mutex
+}
+
+// Write logs the complete lines of p, buffering the last partial one.
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	lw.buf = append(lw.buf, p...)
+	for {
+		i := bytes.IndexByte(lw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		lw.logLine(lw.buf[:i])
+		lw.buf = lw.buf[i+1:]
+	}
+	if len(lw.buf) == 0 {
+		lw.buf = nil
+	}
+	return len(p), nil
+}
+
+// Close logs the remaining partial line.
+func (lw *lineWriter) Close() error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	if len(lw.buf) != 0 {
+		lw.logLine(lw.buf)
+		lw.buf = nil
+	}
+	return nil
+}
+
+func (lw *lineWriter) logLine(line []byte) {
+	ctx := context.Background()
+	if !lw.lgr.load().Enabled(ctx, lw.level) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), lw.level, string(bytes.TrimSuffix(line, []byte{'\r'})), 0)
+	if lw.prefix != "" {
+		r.AddAttrs(slog.String(WriterPrefixKey, lw.prefix))
+	}
+	_ = lw.lgr.Handle(ctx, r)
+}